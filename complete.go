@@ -1,9 +1,7 @@
 package readline
 
 import (
-	"bufio"
 	"bytes"
-	"fmt"
 	"io"
 )
 
@@ -41,7 +39,7 @@ func newOpCompleter(w io.Writer, op *Operation, width int) *opCompleter {
 
 func (o *opCompleter) doSelect() {
 	if len(o.candidates) == 1 {
-		o.op.buf.ReplaceRunes(o.candidates[0].Name, o.candidateOff, o.candidates[0].FormatAsIdentifier, o.candidates[0].AppendSpace)
+		o.insert(o.candidates[0], o.candidateOff)
 		o.ExitCompleteMode(false)
 		return
 	}
@@ -49,6 +47,12 @@ func (o *opCompleter) doSelect() {
 	o.CompleteRefresh()
 }
 
+func (o *opCompleter) insert(c Candidate, offset int) {
+	if stops := o.op.buf.InsertCandidate(c, offset); 0 < len(stops) {
+		o.op.EnterSnippetMode(stops)
+	}
+}
+
 func (o *opCompleter) nextCandidate(i int) {
 	o.candidateChoise += i
 	o.candidateChoise = o.candidateChoise % len(o.candidates)
@@ -90,7 +94,7 @@ func (o *opCompleter) OnComplete() bool {
 
 	if !o.IsInCompleteMode() {
 		if len(newLines) == 1 {
-			o.op.buf.ReplaceRunes(newLines[0].Name, offset, newLines[0].FormatAsIdentifier, newLines[0].AppendSpace)
+			o.insert(newLines[0], offset)
 			o.ExitCompleteMode(false)
 			return true
 		}
@@ -113,7 +117,7 @@ func (o *opCompleter) HandleCompleteSelect(r rune) bool {
 	switch r {
 	case CharEnter, CharCtrlJ:
 		next = false
-		o.op.buf.ReplaceRunes(o.op.candidates[o.op.candidateChoise].Name, o.op.candidateOff, o.op.candidates[o.op.candidateChoise].FormatAsIdentifier, o.op.candidates[o.op.candidateChoise].AppendSpace)
+		o.insert(o.op.candidates[o.op.candidateChoise], o.op.candidateOff)
 		o.ExitCompleteMode(false)
 	case CharLineStart:
 		num := o.candidateChoise % o.candidateColNum
@@ -141,7 +145,7 @@ func (o *opCompleter) HandleCompleteSelect(r rune) bool {
 			tmpChoise -= o.getMatrixSize()
 		}
 		o.candidateChoise = tmpChoise
-	case CharBackward:
+	case CharBackward, CharShiftTab:
 		o.nextCandidate(-1)
 	case CharPrev:
 		tmpChoise := o.candidateChoise - o.candidateColNum
@@ -179,7 +183,6 @@ func (o *opCompleter) CompleteRefresh() {
 	if !o.inCompleteMode {
 		return
 	}
-	lineCnt := o.op.buf.CursorLineCount()
 	colWidth := 0
 	for _, c := range o.candidates {
 		w := runes.WidthAll(c.Name)
@@ -197,12 +200,9 @@ func (o *opCompleter) CompleteRefresh() {
 	}
 
 	o.candidateColNum = colNum
-	buf := bufio.NewWriter(o.w)
-	buf.Write(bytes.Repeat([]byte("\n"), lineCnt))
+	buf := bytes.NewBuffer(nil)
 
 	colIdx := 0
-	lines := 1
-	buf.WriteString("\033[J")
 	for idx, c := range o.candidates {
 		inSelect := idx == o.candidateChoise && o.IsInCompleteSelectMode()
 		if inSelect {
@@ -216,17 +216,13 @@ func (o *opCompleter) CompleteRefresh() {
 		}
 
 		colIdx++
-		if colIdx == colNum {
+		if colIdx == colNum && idx < len(o.candidates)-1 {
 			buf.WriteString("\n")
-			lines++
 			colIdx = 0
 		}
 	}
 
-	// move back
-	fmt.Fprintf(buf, "\033[%dA\r", lineCnt-1+lines)
-	fmt.Fprintf(buf, "\033[%dC", o.op.buf.idx+o.op.buf.PromptLen())
-	buf.Flush()
+	o.op.buf.PrintBelow(buf.String(), o.op.buf.Pos())
 }
 
 func (o *opCompleter) aggCandidate(candidate [][]rune) int {
@@ -29,6 +29,94 @@ type Candidate struct {
 	Name               []rune
 	FormatAsIdentifier bool
 	AppendSpace        bool
+
+	// Insertion overrides the default insertion of Name
+	Insertion *Insertion
+}
+
+// Insertion describes how a candidate is inserted into the buffer.
+type Insertion struct {
+	// Text is inserted in place of the candidate name.
+	Text []rune
+
+	// Start and End are the offsets relative to the cursor of the range
+	// replaced by Text. If both are zero, the offset returned by the
+	// completer is used, that is, the range ends at the cursor, and a
+	// space is inserted before Text if it follows a token like
+	// ReplaceRunes does.
+	Start int
+	End   int
+
+	// Close is inserted after Text unless it already follows the range.
+	Close []rune
+
+	// CursorOffset moves the cursor back from the end of Text and Close.
+	// If it is zero, the cursor is placed at the end of the insertion.
+	CursorOffset int
+
+	// Snippet expands Text with ParseSnippet and starts a session that
+	// moves between the tab stops.
+	Snippet bool
+}
+
+// FunctionCandidate returns a candidate that inserts a function call
+// and leaves the cursor between the parentheses.
+func FunctionCandidate(name string) Candidate {
+	return Candidate{
+		Name:        []rune(name + "() "),
+		AppendSpace: true,
+		Insertion: &Insertion{
+			Text:         []rune(name + "("),
+			Close:        []rune(")"),
+			CursorOffset: 1,
+		},
+	}
+}
+
+// AnalyticFunctionCandidate returns a candidate that inserts a call of
+// an analytic function followed by an empty OVER clause and leaves the
+// cursor between the parentheses of the call.
+func AnalyticFunctionCandidate(name string) Candidate {
+	return Candidate{
+		Name:        []rune(name + "() OVER () "),
+		AppendSpace: true,
+		Insertion: &Insertion{
+			Text:         []rune(name + "("),
+			Close:        []rune(") OVER ()"),
+			CursorOffset: 9,
+		},
+	}
+}
+
+// IdentifierCandidate returns a candidate that inserts name enclosed in
+// backquotes if necessary. The cursor stays in the backquotes if name is
+// a directory. The candidate matches the prefixes enclosed in backquotes.
+func IdentifierCandidate(name string, appendSpace bool) Candidate {
+	if strings.TrimSpace(name) == "" {
+		return Candidate{
+			Name:               []rune(name + " "),
+			FormatAsIdentifier: true,
+			AppendSpace:        appendSpace,
+			Insertion:          &Insertion{Text: []rune(name)},
+		}
+	}
+
+	ident, offset := FormatAsIdentifier([]rune(name + " "))
+	ident = ident[:len(ident)-1]
+	ins := &Insertion{Text: ident}
+	if ident[0] == '`' {
+		ins.Text = ident[:len(ident)-1]
+		ins.Close = []rune{'`'}
+		if 0 < offset {
+			ins.CursorOffset = 1
+		}
+	}
+	return Candidate{
+		Name:               []rune(name + " "),
+		FormatAsIdentifier: true,
+		AppendSpace:        appendSpace,
+		Insertion:          ins,
+	}
 }
 
 func (cand Candidate) StringName() string {
@@ -61,6 +149,11 @@ type PrefixCompleter struct {
 	FormatAsIdentifier bool
 	AppendSpace        bool
 	AppendOnly         bool
+
+	// Description is shown in the tree
+	Description string
+	// Provider is the name of the callback in a CompletionSpec
+	Provider string
 }
 
 func (p *PrefixCompleter) Tree(prefix string) string {
@@ -71,6 +164,9 @@ func (p *PrefixCompleter) Tree(prefix string) string {
 
 func Print(p PrefixCompleterInterface, prefix string, level int, buf *bytes.Buffer) {
 	cand := p.GetName()
+	if pc, ok := p.(*PrefixCompleter); ok && pc.Dynamic && pc.Provider != "" {
+		cand.Name = []rune("$" + pc.Provider + " ")
+	}
 	if strings.TrimSpace(cand.StringName()) != "" {
 		buf.WriteString(prefix)
 		if level > 0 {
@@ -78,10 +174,23 @@ func Print(p PrefixCompleterInterface, prefix string, level int, buf *bytes.Buff
 			buf.WriteString(strings.Repeat("─", (level*4)-2))
 			buf.WriteString(" ")
 		}
-		buf.WriteString(cand.StringName() + "\n")
+		buf.WriteString(cand.StringName())
+		for _, ch := range p.GetChildren() {
+			if ch == p {
+				buf.WriteString("... ")
+				break
+			}
+		}
+		if pc, ok := p.(*PrefixCompleter); ok && pc.Description != "" {
+			buf.WriteString("# " + pc.Description)
+		}
+		buf.WriteString("\n")
 		level++
 	}
 	for _, ch := range p.GetChildren() {
+		if ch == p {
+			continue
+		}
 		ch.Print(prefix, level, buf)
 	}
 }
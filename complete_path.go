@@ -0,0 +1,173 @@
+package readline
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// PathCompleter completes file system paths.
+// Paths containing characters that are not allowed in identifiers are
+// enclosed in backquotes, and paths starting with a single or double
+// quotation mark keep that quotation mark.
+type PathCompleter struct {
+	// Dir is the base directory of relative paths.
+	// The working directory is used if it is empty.
+	Dir string
+
+	// Extensions limits files to the specified extensions such as ".csv".
+	// Directories are always listed.
+	Extensions []string
+
+	// ShowHidden lists files beginning with a dot
+	// even if the typed name does not begin with a dot.
+	ShowHidden bool
+}
+
+// PathElement returns the path that ends at the end of line,
+// which can begin with an unclosed quotation mark.
+func PathElement(line []rune) []rune {
+	for _, q := range []rune{'`', '"', '\''} {
+		if LiteralIsEnclosed(q, line) {
+			continue
+		}
+		for i := len(line) - 1; i >= 0; i-- {
+			if line[i] == q && (i == 0 || line[i-1] != '\\') {
+				return line[i:]
+			}
+		}
+	}
+
+	i := len(line)
+	for 0 < i && !unicode.IsSpace(line[i-1]) && !(IsUniqueToken(line[i-1]) && line[i-1] != '.') {
+		i--
+	}
+	return line[i:]
+}
+
+func unquotePath(raw []rune) (string, rune) {
+	if len(raw) < 1 || !IsQuotationMark(raw[0]) {
+		return string(raw), 0
+	}
+
+	quote := raw[0]
+	buf := make([]rune, 0, len(raw))
+	for i := 1; i < len(raw); i++ {
+		if raw[i] == '\\' && i+1 < len(raw) && raw[i+1] == quote {
+			i++
+		}
+		buf = append(buf, raw[i])
+	}
+	return string(buf), quote
+}
+
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~"+string(os.PathSeparator)) && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return home + path[1:]
+}
+
+func (c *PathCompleter) matchExtension(name string) bool {
+	if len(c.Extensions) < 1 {
+		return true
+	}
+	ext := filepath.Ext(name)
+	for _, e := range c.Extensions {
+		if strings.EqualFold(ext, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// Paths returns the paths that begin with path.
+// Directories end with a path separator. A leading "~" is expanded only to
+// look up the directory, and is kept in the returned paths.
+func (c *PathCompleter) Paths(path string) []string {
+	dir, base := path, ""
+	if i := strings.LastIndexAny(path, "/"+string(os.PathSeparator)); -1 < i {
+		dir, base = path[:i+1], path[i+1:]
+	} else {
+		dir = ""
+		base = path
+	}
+
+	lookup := expandHome(dir)
+	if lookup == "" {
+		lookup = "."
+	}
+	if !filepath.IsAbs(lookup) && c.Dir != "" {
+		lookup = filepath.Join(c.Dir, lookup)
+	}
+
+	entries, err := os.ReadDir(lookup)
+	if err != nil {
+		return nil
+	}
+
+	paths := make([]string, 0, len(entries))
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasPrefix(name, base) {
+			continue
+		}
+		if name[0] == '.' && !c.ShowHidden && (len(base) < 1 || base[0] != '.') {
+			continue
+		}
+
+		isDir := e.IsDir()
+		if !isDir && e.Type()&os.ModeSymlink != 0 {
+			if fi, err := os.Stat(filepath.Join(lookup, name)); err == nil {
+				isDir = fi.IsDir()
+			}
+		}
+
+		if isDir {
+			paths = append(paths, dir+name+string(os.PathSeparator))
+		} else if c.matchExtension(name) {
+			paths = append(paths, dir+name)
+		}
+	}
+	return paths
+}
+
+func (c *PathCompleter) Do(line []rune, pos int, index int) (newLine CandidateList, offset int) {
+	raw := PathElement(line[:pos])
+	path, quote := unquotePath(raw)
+
+	for _, p := range c.Paths(path) {
+		switch quote {
+		case '"', '\'':
+			newLine = append(newLine, quotedPathCandidate(p, quote))
+		default:
+			newLine = append(newLine, IdentifierCandidate(p, true))
+		}
+	}
+	return newLine, len(raw)
+}
+
+// quotedPathCandidate returns a candidate that inserts p enclosed in quote.
+// The cursor stays in the quotes if p is a directory.
+func quotedPathCandidate(p string, quote rune) Candidate {
+	text := string(quote) + strings.ReplaceAll(p, string(quote), "\\"+string(quote))
+	ins := &Insertion{
+		Text:  []rune(text),
+		Close: []rune{quote},
+	}
+	name := text + string(quote)
+	if strings.HasSuffix(p, "/") || strings.HasSuffix(p, string(os.PathSeparator)) {
+		ins.CursorOffset = 1
+		name = text
+	}
+	return Candidate{
+		Name:        []rune(name + " "),
+		AppendSpace: true,
+		Insertion:   ins,
+	}
+}
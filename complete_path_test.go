@@ -0,0 +1,169 @@
+package readline
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var pathElementTests = []struct {
+	Input  string
+	Expect string
+}{
+	{
+		Input:  "SELECT * FROM data.c",
+		Expect: "data.c",
+	},
+	{
+		Input:  "SELECT * FROM `my dir/da",
+		Expect: "`my dir/da",
+	},
+	{
+		Input:  "LOAD \"my dir/da",
+		Expect: "\"my dir/da",
+	},
+	{
+		Input:  "SELECT * FROM `a.csv` NATURAL JOIN b",
+		Expect: "b",
+	},
+	{
+		Input:  "SELECT * FROM ",
+		Expect: "",
+	},
+}
+
+func TestPathElement(t *testing.T) {
+	for _, v := range pathElementTests {
+		result := string(PathElement([]rune(v.Input)))
+		if result != v.Expect {
+			t.Errorf("result = %q, want %q for %q", result, v.Expect, v.Input)
+		}
+	}
+}
+
+func TestPathCompleter_Do(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"data.csv", "data.txt", "my file.json", ".hidden.csv"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	sep := string(os.PathSeparator)
+	tests := []struct {
+		Input      string
+		Extensions []string
+		ShowHidden bool
+		Expect     []string
+		Offset     int
+	}{
+		{
+			Input:      "FROM da",
+			Extensions: []string{".csv"},
+			Expect:     []string{"data.csv "},
+			Offset:     2,
+		},
+		{
+			Input:  "FROM ",
+			Expect: []string{"data.csv ", "data.txt ", "my file.json ", "sub dir" + sep + " "},
+			Offset: 0,
+		},
+		{
+			Input:      "FROM ",
+			ShowHidden: true,
+			Extensions: []string{".CSV"},
+			Expect:     []string{".hidden.csv ", "data.csv ", "sub dir" + sep + " "},
+			Offset:     0,
+		},
+		{
+			Input:  "FROM `su",
+			Expect: []string{"sub dir" + sep + " "},
+			Offset: 3,
+		},
+		{
+			Input:  "LOAD \"s",
+			Expect: []string{"\"sub dir" + sep + " "},
+			Offset: 2,
+		},
+		{
+			Input:  "LOAD 'my",
+			Expect: []string{"'my file.json' "},
+			Offset: 3,
+		},
+	}
+
+	for _, v := range tests {
+		c := &PathCompleter{Dir: dir, Extensions: v.Extensions, ShowHidden: v.ShowHidden}
+		line := []rune(v.Input)
+		list, offset := c.Do(line, len(line), len(line))
+		var result []string
+		for _, cand := range list {
+			result = append(result, cand.StringName())
+		}
+		if !reflect.DeepEqual(result, v.Expect) {
+			t.Errorf("result = %q, want %q for %q", result, v.Expect, v.Input)
+		}
+		if offset != v.Offset {
+			t.Errorf("offset = %d, want %d for %q", offset, v.Offset, v.Input)
+		}
+	}
+}
+
+func TestPathCompleter_Insert(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "sub dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "my file.json"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sep := string(os.PathSeparator)
+	tests := []struct {
+		Input     string
+		Expect    string
+		ExpectIdx int
+	}{
+		{Input: "LOAD \"s", Expect: "LOAD \"sub dir" + sep + "\" ", ExpectIdx: 14},
+		{Input: "LOAD 'my", Expect: "LOAD 'my file.json' ", ExpectIdx: 20},
+	}
+	c := &PathCompleter{Dir: dir}
+	for _, v := range tests {
+		line := []rune(v.Input)
+		list, offset := c.Do(line, len(line), len(line))
+		if len(list) != 1 {
+			t.Fatalf("result = %v for %q", list, v.Input)
+		}
+		buf := new(RuneBuffer)
+		buf.WriteString(v.Input)
+		buf.InsertCandidate(list[0], offset)
+		if string(buf.Runes()) != v.Expect || buf.Pos() != v.ExpectIdx {
+			t.Errorf("result = %q (%d), want %q (%d) for %q", string(buf.Runes()), buf.Pos(), v.Expect, v.ExpectIdx, v.Input)
+		}
+	}
+}
+
+func TestPathCompleter_Home(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	if err := os.WriteFile(filepath.Join(home, "users.csv"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &PathCompleter{}
+	result := c.Paths("~/us")
+	expect := []string{"~/users.csv"}
+	if !reflect.DeepEqual(result, expect) {
+		t.Errorf("result = %q, want %q", result, expect)
+	}
+
+	list, offset := c.Do([]rune("FROM ~/us"), 9, 9)
+	if len(list) != 1 || list[0].StringName() != "~/users.csv " || offset != 4 {
+		t.Errorf("result = %v (%d), want %q (%d)", list, offset, "~/users.csv ", 4)
+	}
+}
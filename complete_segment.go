@@ -1,5 +1,9 @@
 package readline
 
+import (
+	"unicode"
+)
+
 type SegmentCompleter interface {
 	// a
 	// |- a1
@@ -51,31 +55,154 @@ func RetSegment(segments [][]rune, cands [][]rune, idx int) (CandidateList, int)
 	return ret, idx
 }
 
-func SplitSegment(line []rune, pos int) ([][]rune, int) {
-	segs := [][]rune{}
-	lastIdx := -1
+// Segment is an argument in a line.
+// Value is unquoted and unescaped, and Start and End are the offsets of
+// the raw text in the line.
+type Segment struct {
+	Value []rune
+	Start int
+	End   int
+
+	// Quote is the quotation mark enclosing the segment, or 0.
+	Quote rune
+}
+
+// ParseSegments splits line[:pos] into arguments in the manner of a shell.
+// Arguments are separated by spaces, and single, double and back quotes
+// and backslash escapes are recognized.
+// If line[:pos] is empty or ends with a separator, an empty segment is added.
+func ParseSegments(line []rune, pos int) []Segment {
 	line = line[:pos]
-	pos = 0
-	for idx, l := range line {
-		if l == ' ' {
-			pos = 0
-			segs = append(segs, line[lastIdx+1:idx])
-			lastIdx = idx
-		} else {
-			pos++
+	segs := []Segment{}
+
+	var seg *Segment
+	var quote rune
+	for i := 0; i < len(line); i++ {
+		r := line[i]
+		if seg == nil {
+			if quote == 0 && unicode.IsSpace(r) {
+				continue
+			}
+			seg = &Segment{Value: []rune{}, Start: i}
+		}
+
+		switch {
+		case quote != 0:
+			switch {
+			case r == '\\' && i+1 < len(line) && (line[i+1] == quote || line[i+1] == '\\'):
+				i++
+				seg.Value = append(seg.Value, line[i])
+			case r == quote:
+				quote = 0
+			default:
+				seg.Value = append(seg.Value, r)
+			}
+		case r == '\\':
+			if i+1 < len(line) {
+				i++
+				seg.Value = append(seg.Value, line[i])
+			}
+		case IsQuotationMark(r):
+			quote = r
+			if seg.Quote == 0 && i == seg.Start {
+				seg.Quote = r
+			}
+		case unicode.IsSpace(r):
+			seg.End = i
+			segs = append(segs, *seg)
+			seg = nil
+		default:
+			seg.Value = append(seg.Value, r)
+		}
+	}
+
+	if seg == nil {
+		segs = append(segs, Segment{Value: []rune{}, Start: len(line), End: len(line)})
+	} else {
+		if quote != 0 {
+			seg.Quote = quote
+		}
+		seg.End = len(line)
+		segs = append(segs, *seg)
+	}
+	return segs
+}
+
+func needsSegmentQuote(s []rune) bool {
+	for _, r := range s {
+		if unicode.IsSpace(r) || IsQuotationMark(r) || r == '\\' {
+			return true
+		}
+	}
+	return false
+}
+
+// QuoteSegment encloses s with quote, or escapes it with backslashes if
+// quote is 0.
+func QuoteSegment(s []rune, quote rune) []rune {
+	ret := make([]rune, 0, len(s)+2)
+	if quote != 0 {
+		ret = append(ret, quote)
+	}
+	for _, r := range s {
+		switch {
+		case quote == 0 && (unicode.IsSpace(r) || IsQuotationMark(r) || r == '\\'):
+			ret = append(ret, '\\')
+		case quote != 0 && (r == quote || r == '\\'):
+			ret = append(ret, '\\')
 		}
+		ret = append(ret, r)
 	}
-	segs = append(segs, line[lastIdx+1:])
-	return segs, pos
+	if quote != 0 {
+		ret = append(ret, quote)
+	}
+	return ret
+}
+
+func SplitSegment(line []rune, pos int) ([][]rune, int) {
+	segments := ParseSegments(line, pos)
+	segs := make([][]rune, 0, len(segments))
+	for _, s := range segments {
+		segs = append(segs, s.Value)
+	}
+	return segs, len(segs[len(segs)-1])
 }
 
 func (c *SegmentComplete) Do(line []rune, pos int, index int) (newLine CandidateList, offset int) {
-	segment, idx := SplitSegment(line, pos)
+	segments := ParseSegments(line, pos)
+	segment := make([][]rune, 0, len(segments))
+	for _, s := range segments {
+		segment = append(segment, s.Value)
+	}
+	last := segments[len(segments)-1]
+	idx := len(last.Value)
 
 	cands := c.DoSegment(segment, idx)
-	newLine, offset = RetSegment(segment, cands, idx)
+
+	requote := last.Quote != 0
+	for _, cand := range cands {
+		if needsSegmentQuote(cand) && runes.HasPrefixFold(cand, last.Value, false) {
+			requote = true
+			break
+		}
+	}
+
+	if requote {
+		// replace the whole raw segment with the quoted candidate
+		for _, cand := range cands {
+			if !runes.HasPrefixFold(cand, last.Value, false) {
+				continue
+			}
+			name := append(QuoteSegment(cand, last.Quote), ' ')
+			newLine = append(newLine, Candidate{Name: name, FormatAsIdentifier: false, AppendSpace: true})
+		}
+		return newLine, last.End - last.Start
+	}
+
+	// the offset is the length of the raw segment including the escapes
+	newLine, _ = RetSegment(segment, cands, idx)
 	for idx := range newLine {
 		newLine[idx].Name = append(newLine[idx].Name, ' ')
 	}
-	return newLine, offset
+	return newLine, last.End - last.Start
 }
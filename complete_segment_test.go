@@ -160,6 +160,8 @@ func TestSegmentCompleter(t *testing.T) {
 		{"a a1 a1", 7, sr("1", "2"), 2},
 		{"a a1 a11", 8, sr(""), 3},
 		{"route add", 9, sr("", "domain"), 3},
+		{"a a\\1", 5, sr(""), 3},
+		{"a a1 a\\1", 8, sr("1", "2"), 3},
 	}
 	for _, r := range ret {
 		for idx, rr := range r.Ret {
@@ -181,3 +183,98 @@ func TestSegmentCompleter(t *testing.T) {
 		}
 	}
 }
+
+func TestParseSegments(t *testing.T) {
+	ret := []struct {
+		Line     string
+		Segments []Segment
+	}{
+		{"", []Segment{{Value: []rune{}, Start: 0, End: 0}}},
+		{"a  b", []Segment{
+			{Value: []rune("a"), Start: 0, End: 1},
+			{Value: []rune("b"), Start: 3, End: 4},
+		}},
+		{"a\tb ", []Segment{
+			{Value: []rune("a"), Start: 0, End: 1},
+			{Value: []rune("b"), Start: 2, End: 3},
+			{Value: []rune{}, Start: 4, End: 4},
+		}},
+		{"a\\ b c", []Segment{
+			{Value: []rune("a b"), Start: 0, End: 4},
+			{Value: []rune("c"), Start: 5, End: 6},
+		}},
+		{"'a b' \"c \\\" d", []Segment{
+			{Value: []rune("a b"), Start: 0, End: 5, Quote: '\''},
+			{Value: []rune("c \" d"), Start: 6, End: 13, Quote: '"'},
+		}},
+		{"x`a b`y `c", []Segment{
+			{Value: []rune("xa by"), Start: 0, End: 7},
+			{Value: []rune("c"), Start: 8, End: 10, Quote: '`'},
+		}},
+	}
+
+	for i, r := range ret {
+		line := []rune(r.Line)
+		segs := ParseSegments(line, len(line))
+		if !reflect.DeepEqual(segs, r.Segments) {
+			t.Errorf("incorrect segments %+v at %d", segs, i)
+		}
+	}
+}
+
+func TestQuoteSegment(t *testing.T) {
+	ret := []struct {
+		Input  string
+		Quote  rune
+		Expect string
+	}{
+		{"abc", 0, "abc"},
+		{"a b'c", 0, "a\\ b\\'c"},
+		{"a b\"c", '"', "\"a b\\\"c\""},
+		{"a b", '\'', "'a b'"},
+	}
+	for _, r := range ret {
+		result := string(QuoteSegment([]rune(r.Input), r.Quote))
+		if result != r.Expect {
+			t.Errorf("result = %q, want %q for %q", result, r.Expect, r.Input)
+		}
+	}
+}
+
+func TestSegmentCompleter_Quote(t *testing.T) {
+	s := SegmentFunc(func(ret [][]rune, n int) [][]rune {
+		if len(ret) == 1 {
+			return sr("open")
+		}
+		if string(ret[0]) != "open" {
+			return nil
+		}
+		return sr("my file.csv", "my data.csv", "other.csv")
+	})
+
+	ret := []struct {
+		Line   string
+		Ret    []string
+		Offset int
+	}{
+		{"open  o", []string{"ther.csv "}, 1},
+		{"open my", []string{"my\\ file.csv ", "my\\ data.csv "}, 2},
+		{"open \"my f", []string{"\"my file.csv\" "}, 5},
+		{"open 'my\\ ", nil, 5},
+		{"open my\\ d", []string{"my\\ data.csv "}, 5},
+	}
+	for i, r := range ret {
+		line := []rune(r.Line)
+		newLine, offset := s.Do(line, len(line), len(line))
+		var lines []string
+		for _, v := range newLine {
+			lines = append(lines, v.StringName())
+		}
+		if !reflect.DeepEqual(lines, r.Ret) {
+			t.Errorf("incorrect lines %q at %d", lines, i)
+		}
+		if offset != r.Offset {
+			t.Errorf("incorrect offset %d at %d", offset, i)
+		}
+	}
+}
@@ -0,0 +1,329 @@
+package readline
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// CompletionSpec is a declarative definition of a completion tree.
+//
+// A node is one of a keyword (Name), a named dynamic provider (Provider)
+// or a group of Alternatives. Children are the alternatives for the
+// position that follows the node.
+//
+// A spec is written in JSON, or in a indented text format that has one
+// node per line:
+//
+//	SELECT              # retrieve rows
+//	  [DISTINCT]
+//	    $columns ...
+//	      FROM
+//	        $tables
+//	INNER | LEFT        # alternatives sharing the children
+//	  JOIN
+//
+// "[node]" marks the node as optional, "node ..." marks it as repeatable,
+// "$name" refers to a provider and "# text" is a description.
+type CompletionSpec struct {
+	Name         string            `json:"name,omitempty"`
+	Provider     string            `json:"provider,omitempty"`
+	Description  string            `json:"description,omitempty"`
+	Identifier   bool              `json:"identifier,omitempty"`
+	AppendOnly   bool              `json:"append_only,omitempty"`
+	Optional     bool              `json:"optional,omitempty"`
+	Repeat       bool              `json:"repeat,omitempty"`
+	Alternatives []*CompletionSpec `json:"alternatives,omitempty"`
+	Children     []*CompletionSpec `json:"children,omitempty"`
+}
+
+// LoadCompletionSpec reads a spec from r and builds the completion tree.
+func LoadCompletionSpec(r io.Reader, providers map[string]DynamicCompleteFunc) (*PrefixCompleter, error) {
+	spec, err := ParseCompletionSpec(r)
+	if err != nil {
+		return nil, err
+	}
+	return spec.Build(providers)
+}
+
+// ParseCompletionSpec reads a spec in JSON or in the indented text format.
+func ParseCompletionSpec(r io.Reader) (*CompletionSpec, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	if 0 < len(trimmed) && (trimmed[0] == '{' || trimmed[0] == '[') {
+		return parseJSONCompletionSpec(trimmed)
+	}
+	return parseTextCompletionSpec(data)
+}
+
+func parseJSONCompletionSpec(data []byte) (*CompletionSpec, error) {
+	spec := &CompletionSpec{}
+	if data[0] == '[' {
+		if err := json.Unmarshal(data, &spec.Children); err != nil {
+			return nil, err
+		}
+		return spec, nil
+	}
+	if err := json.Unmarshal(data, spec); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+func parseTextCompletionSpecNode(text string) (*CompletionSpec, error) {
+	node := &CompletionSpec{}
+	if strings.HasSuffix(text, "...") {
+		node.Repeat = true
+		text = strings.TrimSpace(text[:len(text)-3])
+	}
+	if strings.HasPrefix(text, "[") && strings.HasSuffix(text, "]") {
+		node.Optional = true
+		text = strings.TrimSpace(text[1 : len(text)-1])
+	}
+
+	alts := strings.Split(text, "|")
+	if 1 < len(alts) {
+		for _, alt := range alts {
+			child, err := parseTextCompletionSpecNode(strings.TrimSpace(alt))
+			if err != nil {
+				return nil, err
+			}
+			node.Alternatives = append(node.Alternatives, child)
+		}
+		return node, nil
+	}
+
+	if strings.HasPrefix(text, "$") {
+		node.Provider = text[1:]
+	} else {
+		node.Name = text
+	}
+	return node, nil
+}
+
+func parseTextCompletionSpec(data []byte) (*CompletionSpec, error) {
+	type level struct {
+		indent int
+		node   *CompletionSpec
+	}
+
+	root := &CompletionSpec{}
+	stack := []level{{indent: -1, node: root}}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.ReplaceAll(scanner.Text(), "\t", strings.Repeat(" ", TabWidth))
+		text := strings.TrimSpace(line)
+		if text == "" || text[0] == '#' {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+
+		description := ""
+		if i := strings.Index(text, " #"); -1 < i {
+			description = strings.TrimSpace(text[i+2:])
+			text = strings.TrimSpace(text[:i])
+		}
+
+		node, err := parseTextCompletionSpecNode(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", lineNum, err.Error())
+		}
+		node.Description = description
+
+		for indent <= stack[len(stack)-1].indent {
+			stack = stack[:len(stack)-1]
+		}
+		parent := stack[len(stack)-1].node
+		parent.Children = append(parent.Children, node)
+		stack = append(stack, level{indent: indent, node: node})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// Validate checks that the spec can be built with the providers.
+func (s *CompletionSpec) Validate(providers map[string]DynamicCompleteFunc) error {
+	return s.validate(providers, "root")
+}
+
+func (s *CompletionSpec) validate(providers map[string]DynamicCompleteFunc, path string) error {
+	kinds := 0
+	if s.Name != "" {
+		kinds++
+	}
+	if s.Provider != "" {
+		kinds++
+		if _, ok := providers[s.Provider]; !ok {
+			return fmt.Errorf("%s: provider %q is not defined", path, s.Provider)
+		}
+	}
+	if 0 < len(s.Alternatives) {
+		kinds++
+		if s.Repeat {
+			return fmt.Errorf("%s: alternatives cannot be repeated", path)
+		}
+		for i, alt := range s.Alternatives {
+			if 0 < len(alt.Children) {
+				return fmt.Errorf("%s: alternative %d has children", path, i)
+			}
+			if err := alt.validate(providers, fmt.Sprintf("%s/%d", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+	if path != "root" && kinds != 1 {
+		return fmt.Errorf("%s: node must have exactly one of name, provider and alternatives", path)
+	}
+
+	names := make(map[string]bool)
+	for _, child := range s.Children {
+		childPath := path + "/" + child.label()
+		for _, n := range child.keywords() {
+			key := strings.ToUpper(n)
+			if names[key] {
+				return fmt.Errorf("%s: duplicate name %q", path, n)
+			}
+			names[key] = true
+		}
+		if err := child.validate(providers, childPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *CompletionSpec) label() string {
+	switch {
+	case s.Name != "":
+		return s.Name
+	case s.Provider != "":
+		return "$" + s.Provider
+	}
+	labels := make([]string, 0, len(s.Alternatives))
+	for _, alt := range s.Alternatives {
+		labels = append(labels, alt.label())
+	}
+	return strings.Join(labels, "|")
+}
+
+func (s *CompletionSpec) keywords() []string {
+	if s.Name != "" {
+		return []string{s.Name}
+	}
+	var names []string
+	for _, alt := range s.Alternatives {
+		names = append(names, alt.keywords()...)
+	}
+	return names
+}
+
+// Build validates the spec and builds the completion tree.
+func (s *CompletionSpec) Build(providers map[string]DynamicCompleteFunc) (*PrefixCompleter, error) {
+	if err := s.Validate(providers); err != nil {
+		return nil, err
+	}
+
+	var children []PrefixCompleterInterface
+	for _, child := range s.Children {
+		children = append(children, child.build(providers, nil)...)
+	}
+	return NewPrefixCompleter(children...), nil
+}
+
+// build returns the items this node adds to its parent.
+// follow is the continuation after the node and its children.
+func (s *CompletionSpec) build(providers map[string]DynamicCompleteFunc, follow []PrefixCompleterInterface) []PrefixCompleterInterface {
+	next := follow
+	if 0 < len(s.Children) {
+		next = nil
+		for _, child := range s.Children {
+			next = append(next, child.build(providers, follow)...)
+		}
+	}
+
+	var items []PrefixCompleterInterface
+	switch {
+	case 0 < len(s.Alternatives):
+		for _, alt := range s.Alternatives {
+			items = append(items, alt.build(providers, next)...)
+		}
+	default:
+		var item *PrefixCompleter
+		if s.Provider != "" {
+			item = PcItemDynamic(providers[s.Provider], next...)
+			item.Provider = s.Provider
+		} else {
+			item = PcItem(s.Name, next...)
+		}
+		item.Description = s.Description
+		item.FormatAsIdentifier = s.Identifier
+		item.AppendOnly = s.AppendOnly
+		if s.Repeat {
+			item.Children = append(append([]PrefixCompleterInterface{}, next...), item)
+		}
+		items = append(items, item)
+	}
+
+	if s.Optional {
+		items = append(items, next...)
+	}
+	return items
+}
+
+// Spec exports the tree as a spec, which is the supported way to export a
+// completion tree. Building the spec with the same providers gives a tree
+// that completes the same candidates.
+// Optional nodes and alternatives are expanded in the exported spec, and
+// the items other than *PrefixCompleter are exported by their names.
+func (p *PrefixCompleter) Spec() (*CompletionSpec, error) {
+	return exportCompletionSpec(p, nil)
+}
+
+func exportCompletionSpec(p PrefixCompleterInterface, ancestors []PrefixCompleterInterface) (*CompletionSpec, error) {
+	for _, a := range ancestors {
+		if a == p {
+			return nil, errors.New("tree contains a cycle other than repetition")
+		}
+	}
+
+	cand := p.GetName()
+	spec := &CompletionSpec{
+		Name:       strings.TrimSpace(cand.StringName()),
+		Identifier: cand.FormatAsIdentifier,
+		AppendOnly: p.IsAppendOnly(),
+	}
+	if pc, ok := p.(*PrefixCompleter); ok {
+		spec.Description = pc.Description
+		if pc.Dynamic {
+			if pc.Provider == "" {
+				return nil, errors.New("dynamic item has no provider name")
+			}
+			spec.Provider = pc.Provider
+		}
+	}
+
+	ancestors = append(ancestors, p)
+	for _, child := range p.GetChildren() {
+		if child == p {
+			spec.Repeat = true
+			continue
+		}
+		c, err := exportCompletionSpec(child, ancestors)
+		if err != nil {
+			return nil, err
+		}
+		spec.Children = append(spec.Children, c)
+	}
+	return spec, nil
+}
@@ -0,0 +1,165 @@
+package readline
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var completionSpecText = `
+# csvq statements
+SELECT              # retrieve rows
+  [DISTINCT]
+    $columns ...
+      FROM
+        $tables
+INNER | LEFT        # join types
+  JOIN
+    $tables
+`
+
+var completionSpecProviders = map[string]DynamicCompleteFunc{
+	"columns": func(string, string, int) CandidateList {
+		return CandidateList{{Name: []rune("c1"), AppendSpace: true}}
+	},
+	"tables": func(string, string, int) CandidateList {
+		return CandidateList{{Name: []rune("t1"), AppendSpace: true}}
+	},
+}
+
+var completionSpecTests = []struct {
+	Input  string
+	Expect []string
+}{
+	{Input: "SE", Expect: []string{"SELECT "}},
+	{Input: "SELECT ", Expect: []string{"DISTINCT ", "c1 "}},
+	{Input: "SELECT DISTINCT ", Expect: []string{"c1 "}},
+	{Input: "SELECT c1 ", Expect: []string{"FROM ", "c1 "}},
+	{Input: "SELECT c1 c1 F", Expect: []string{"FROM "}},
+	{Input: "SELECT c1 FROM ", Expect: []string{"t1 "}},
+	{Input: "LEFT ", Expect: []string{"JOIN "}},
+	{Input: "INNER JOIN ", Expect: []string{"t1 "}},
+}
+
+func testCompletionSpecTree(t *testing.T, p *PrefixCompleter) {
+	for _, v := range completionSpecTests {
+		line := []rune(v.Input)
+		list, _ := p.Do(line, len(line), len(line))
+		var result []string
+		for _, cand := range list {
+			result = append(result, cand.StringName())
+		}
+		if !reflect.DeepEqual(result, v.Expect) {
+			t.Errorf("result = %q, want %q for %q", result, v.Expect, v.Input)
+		}
+	}
+}
+
+func TestLoadCompletionSpec(t *testing.T) {
+	p, err := LoadCompletionSpec(strings.NewReader(completionSpecText), completionSpecProviders)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCompletionSpecTree(t, p)
+
+	tree := p.Tree("")
+	if !strings.Contains(tree, "SELECT # retrieve rows\n") || !strings.Contains(tree, "$columns ... \n") {
+		t.Errorf("unexpected tree:\n%s", tree)
+	}
+}
+
+func TestLoadCompletionSpec_JSON(t *testing.T) {
+	spec := `[
+		{"name": "OPEN", "children": [{"provider": "tables"}]},
+		{"alternatives": [{"name": "CLOSE"}, {"name": "DISPOSE"}], "optional": true,
+		 "children": [{"name": "CURSOR"}]}
+	]`
+	p, err := LoadCompletionSpec(strings.NewReader(spec), completionSpecProviders)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, ch := range p.GetChildren() {
+		names = append(names, strings.TrimSpace(ch.GetName().StringName()))
+	}
+	expect := []string{"OPEN", "CLOSE", "DISPOSE", "CURSOR"}
+	if !reflect.DeepEqual(names, expect) {
+		t.Errorf("result = %q, want %q", names, expect)
+	}
+}
+
+func TestCompletionSpec_Validate(t *testing.T) {
+	tests := []struct {
+		Spec  string
+		Error string
+	}{
+		{
+			Spec:  "SELECT\n  $unknown",
+			Error: "root/SELECT/$unknown: provider \"unknown\" is not defined",
+		},
+		{
+			Spec:  "SELECT\nselect",
+			Error: "root: duplicate name \"select\"",
+		},
+		{
+			Spec:  "A | B ...",
+			Error: "root/A|B: alternatives cannot be repeated",
+		},
+		{
+			Spec:  `[{"name": "A", "provider": "tables"}]`,
+			Error: "root/A: node must have exactly one of name, provider and alternatives",
+		},
+	}
+	for _, v := range tests {
+		_, err := LoadCompletionSpec(strings.NewReader(v.Spec), completionSpecProviders)
+		if err == nil {
+			t.Errorf("no error, want %q for %q", v.Error, v.Spec)
+		} else if err.Error() != v.Error {
+			t.Errorf("error = %q, want %q for %q", err.Error(), v.Error, v.Spec)
+		}
+	}
+}
+
+func TestPrefixCompleter_Spec(t *testing.T) {
+	p, err := LoadCompletionSpec(strings.NewReader("OPEN # open a cursor\n  $tables ...\n"), completionSpecProviders)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, err := p.Spec()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := &CompletionSpec{
+		Children: []*CompletionSpec{
+			{
+				Name:        "OPEN",
+				Description: "open a cursor",
+				Children: []*CompletionSpec{
+					{Provider: "tables", Repeat: true},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(spec, expect) {
+		t.Errorf("result = %+v, want %+v", spec, expect)
+	}
+
+	// the exported spec builds the same tree
+	p, err = LoadCompletionSpec(strings.NewReader(completionSpecText), completionSpecProviders)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec, err = p.Spec(); err != nil {
+		t.Fatal(err)
+	}
+	if p, err = spec.Build(completionSpecProviders); err != nil {
+		t.Fatal(err)
+	}
+	testCompletionSpecTree(t, p)
+
+	if _, err := NewPrefixCompleter(PcItemDynamic(completionSpecProviders["tables"])).Spec(); err == nil {
+		t.Error("no error for a dynamic item without provider name")
+	}
+}
@@ -0,0 +1,541 @@
+package readline
+
+import (
+	"strings"
+	"unicode"
+)
+
+type SQLTokenType int
+
+const (
+	SQLTokenWord SQLTokenType = iota
+	SQLTokenIdentifier
+	SQLTokenString
+	SQLTokenNumber
+	SQLTokenVariable
+	SQLTokenPunct
+)
+
+// SQLToken is a lexical element of a statement.
+// Start and End are rune offsets in the tokenized line.
+type SQLToken struct {
+	Type    SQLTokenType
+	Literal []rune
+	Start   int
+	End     int
+	Closed  bool
+}
+
+// Value returns the token without its enclosing quotes and escapes.
+func (t SQLToken) Value() string {
+	switch t.Type {
+	case SQLTokenIdentifier, SQLTokenString:
+		if len(t.Literal) < 1 {
+			return ""
+		}
+		quote := t.Literal[0]
+		body := t.Literal[1:]
+		if t.Closed && 0 < len(body) {
+			body = body[:len(body)-1]
+		}
+		buf := make([]rune, 0, len(body))
+		for i := 0; i < len(body); i++ {
+			if body[i] == '\\' && i+1 < len(body) && body[i+1] == quote {
+				i++
+			}
+			buf = append(buf, body[i])
+		}
+		return string(buf)
+	}
+	return string(t.Literal)
+}
+
+func (t SQLToken) IsPunct(r rune) bool {
+	return t.Type == SQLTokenPunct && len(t.Literal) == 1 && t.Literal[0] == r
+}
+
+func (t SQLToken) IsKeyword(keyword string) bool {
+	return t.Type == SQLTokenWord && strings.EqualFold(string(t.Literal), keyword)
+}
+
+func isSQLWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// TokenizeSQL splits line into tokens. Comments and spaces are dropped.
+// Quoted tokens that are not terminated run to the end of the line and
+// have Closed set to false.
+func TokenizeSQL(line []rune) []SQLToken {
+	tokens := make([]SQLToken, 0, 16)
+
+	readQuoted := func(start int) (int, bool) {
+		quote := line[start]
+		for i := start + 1; i < len(line); i++ {
+			switch line[i] {
+			case '\\':
+				if i+1 < len(line) && line[i+1] == quote {
+					i++
+				}
+			case quote:
+				return i + 1, true
+			}
+		}
+		return len(line), false
+	}
+
+	for i := 0; i < len(line); {
+		r := line[i]
+		start := i
+		tok := SQLToken{Start: start, Closed: true}
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			continue
+		case r == '-' && i+1 < len(line) && line[i+1] == '-':
+			for i < len(line) && line[i] != '\n' {
+				i++
+			}
+			continue
+		case r == '/' && i+1 < len(line) && line[i+1] == '*':
+			i += 2
+			for i < len(line) && !(line[i] == '/' && line[i-1] == '*') {
+				i++
+			}
+			i++
+			continue
+		case r == '`':
+			tok.Type = SQLTokenIdentifier
+			i, tok.Closed = readQuoted(i)
+		case r == '\'' || r == '"':
+			tok.Type = SQLTokenString
+			i, tok.Closed = readQuoted(i)
+		case r == '@':
+			tok.Type = SQLTokenVariable
+			i++
+			if i < len(line) && (line[i] == '@' || line[i] == '%' || line[i] == '#') {
+				i++
+			}
+			if i < len(line) && line[i] == '`' {
+				i, tok.Closed = readQuoted(i)
+			} else {
+				for i < len(line) && isSQLWordRune(line[i]) {
+					i++
+				}
+			}
+		case unicode.IsDigit(r):
+			tok.Type = SQLTokenNumber
+			for i < len(line) && (unicode.IsDigit(line[i]) || line[i] == '.') {
+				i++
+			}
+		case isSQLWordRune(r):
+			tok.Type = SQLTokenWord
+			for i < len(line) && isSQLWordRune(line[i]) {
+				i++
+			}
+		default:
+			tok.Type = SQLTokenPunct
+			i++
+		}
+
+		tok.End = i
+		tok.Literal = line[start:i]
+		tokens = append(tokens, tok)
+	}
+	return tokens
+}
+
+type SQLClause int
+
+const (
+	SQLClauseNone SQLClause = iota
+	SQLClauseSelect
+	SQLClauseFrom
+	SQLClauseJoin
+	SQLClauseWhere
+	SQLClauseGroupBy
+	SQLClauseOrderBy
+	SQLClauseFunction
+	SQLClauseCursor
+)
+
+type SQLTableRef struct {
+	Name  string
+	Alias string
+}
+
+// SQLContext describes the position of the cursor in a statement.
+type SQLContext struct {
+	Clause SQLClause
+
+	// Function is the name of the innermost function call
+	// when Clause is SQLClauseFunction.
+	Function string
+
+	// Qualifier is the table name or alias preceding a dot.
+	Qualifier string
+
+	// Prefix is the raw text of the element being completed.
+	Prefix []rune
+
+	// Tables are the tables referenced in the current statement.
+	Tables []SQLTableRef
+}
+
+// Resolve returns the table name for an alias.
+// If no alias matches, name is returned as is.
+func (c *SQLContext) Resolve(name string) string {
+	for _, t := range c.Tables {
+		if strings.EqualFold(t.Alias, name) {
+			return t.Name
+		}
+	}
+	return name
+}
+
+var sqlReservedWords = map[string]bool{
+	"ABSOLUTE": true, "ALL": true, "AND": true, "AS": true, "ASC": true,
+	"BETWEEN": true, "BY": true, "CASE": true, "CLOSE": true, "CROSS": true,
+	"CURSOR": true, "DECLARE": true, "DELETE": true, "DESC": true, "DISPOSE": true,
+	"DISTINCT": true, "ELSE": true, "END": true, "EXCEPT": true, "EXISTS": true,
+	"FETCH": true, "FIRST": true, "FOR": true, "FROM": true, "FULL": true,
+	"GROUP": true, "HAVING": true, "IN": true, "INNER": true, "INSERT": true,
+	"INTERSECT": true, "INTO": true, "IS": true, "JOIN": true, "LAST": true,
+	"LATERAL": true, "LEFT": true, "LIKE": true, "LIMIT": true, "NATURAL": true,
+	"NEXT": true, "NOT": true, "NULL": true, "OFFSET": true, "ON": true,
+	"OPEN": true, "OR": true, "ORDER": true, "OUTER": true, "OVER": true,
+	"PARTITION": true, "PRIOR": true, "RELATIVE": true, "RIGHT": true, "SELECT": true,
+	"SET": true, "THEN": true, "UNION": true, "UPDATE": true, "USING": true,
+	"VALUES": true, "WHEN": true, "WHERE": true, "WITH": true,
+}
+
+func isSQLReserved(t SQLToken) bool {
+	return t.Type == SQLTokenWord && sqlReservedWords[strings.ToUpper(string(t.Literal))]
+}
+
+func isSQLDelimiter(r rune) bool {
+	return unicode.IsSpace(r) || (IsUniqueToken(r) && r != '.')
+}
+
+type sqlFrame struct {
+	clause   SQLClause
+	function string
+}
+
+// ParseSQLContext detects the clause at pos in line and collects the tables
+// referenced by the statement under the cursor.
+func ParseSQLContext(line []rune, pos int) SQLContext {
+	if pos > len(line) {
+		pos = len(line)
+	}
+
+	ctx := SQLContext{}
+	tokens := TokenizeSQL(line)
+
+	// restrict to the statement under the cursor
+	stmtStart, stmtEnd := 0, len(tokens)
+	for i, t := range tokens {
+		if !t.IsPunct(';') {
+			continue
+		}
+		if t.End <= pos {
+			stmtStart = i + 1
+		} else {
+			stmtEnd = i
+			break
+		}
+	}
+	stmt := tokens[stmtStart:stmtEnd]
+
+	current := -1
+	before := make([]SQLToken, 0, len(stmt))
+	for i, t := range stmt {
+		if pos <= t.Start {
+			break
+		}
+		if t.Type == SQLTokenString && (pos < t.End || !t.Closed) {
+			// in a string literal
+			ctx.Prefix = line[t.Start:pos]
+			return ctx
+		}
+		if pos <= t.End && (t.Type == SQLTokenWord || t.Type == SQLTokenIdentifier || t.Type == SQLTokenVariable) {
+			current = i
+			ctx.Prefix = line[t.Start:pos]
+			break
+		}
+		before = append(before, t)
+	}
+	ctx.Tables = collectSQLTables(stmt, current)
+
+	// table names can be file paths, so the prefix runs back to a delimiter
+	if current < 0 || stmt[current].Type == SQLTokenWord {
+		runStart := pos
+		for 0 < runStart && !isSQLDelimiter(line[runStart-1]) {
+			runStart--
+		}
+		tableBefore := before
+		for 0 < len(tableBefore) && runStart < tableBefore[len(tableBefore)-1].End {
+			tableBefore = tableBefore[:len(tableBefore)-1]
+		}
+		if clause, _ := detectSQLClause(tableBefore); clause == SQLClauseFrom || clause == SQLClauseJoin {
+			ctx.Clause = clause
+			ctx.Prefix = line[runStart:pos]
+			return ctx
+		}
+	}
+
+	prefixStart := pos
+	if 0 <= current {
+		prefixStart = stmt[current].Start
+	}
+	if 1 < len(before) {
+		dot := before[len(before)-1]
+		qual := before[len(before)-2]
+		if dot.IsPunct('.') && dot.End == prefixStart && qual.End == dot.Start &&
+			(qual.Type == SQLTokenWord || qual.Type == SQLTokenIdentifier) {
+			ctx.Qualifier = qual.Value()
+			before = before[:len(before)-2]
+		}
+	}
+
+	ctx.Clause, ctx.Function = detectSQLClause(before)
+	return ctx
+}
+
+func detectSQLClause(tokens []SQLToken) (SQLClause, string) {
+	clause := SQLClauseNone
+	function := ""
+	stack := make([]sqlFrame, 0, 4)
+	isFetch := 0 < len(tokens) && tokens[0].IsKeyword("FETCH")
+
+	for i, t := range tokens {
+		switch t.Type {
+		case SQLTokenPunct:
+			switch {
+			case t.IsPunct('('):
+				stack = append(stack, sqlFrame{clause: clause, function: function})
+				if 0 < i && tokens[i-1].Type == SQLTokenWord && !isSQLReserved(tokens[i-1]) {
+					clause = SQLClauseFunction
+					function = string(tokens[i-1].Literal)
+				}
+			case t.IsPunct(')'):
+				if 0 < len(stack) {
+					clause = stack[len(stack)-1].clause
+					function = stack[len(stack)-1].function
+					stack = stack[:len(stack)-1]
+				}
+			}
+		case SQLTokenWord:
+			switch strings.ToUpper(string(t.Literal)) {
+			case "SELECT":
+				clause = SQLClauseSelect
+			case "FROM":
+				if isFetch {
+					clause = SQLClauseCursor
+				} else {
+					clause = SQLClauseFrom
+				}
+			case "UPDATE", "INTO":
+				clause = SQLClauseFrom
+			case "JOIN":
+				clause = SQLClauseJoin
+			case "WHERE", "HAVING", "ON", "SET":
+				clause = SQLClauseWhere
+			case "BY":
+				if 0 < i && tokens[i-1].IsKeyword("ORDER") {
+					clause = SQLClauseOrderBy
+				} else if 0 < i && tokens[i-1].IsKeyword("GROUP") {
+					clause = SQLClauseGroupBy
+				}
+			case "OPEN", "CLOSE", "FETCH", "NEXT", "PRIOR", "FIRST", "LAST":
+				clause = SQLClauseCursor
+			case "CURSOR":
+				if 0 < i && tokens[i-1].IsKeyword("DISPOSE") {
+					clause = SQLClauseCursor
+				}
+			case "LIMIT", "OFFSET", "UNION", "INTERSECT", "EXCEPT":
+				clause = SQLClauseNone
+			}
+		}
+	}
+
+	if len(tokens) < 1 {
+		return clause, function
+	}
+
+	last := tokens[len(tokens)-1]
+	switch clause {
+	case SQLClauseFrom, SQLClauseJoin:
+		// a table is expected only right after the keyword or a comma
+		if !(last.IsKeyword("FROM") || last.IsKeyword("JOIN") || last.IsKeyword("UPDATE") ||
+			last.IsKeyword("INTO") || last.IsPunct(',')) {
+			clause = SQLClauseNone
+		}
+	case SQLClauseCursor:
+		if !(last.IsKeyword("OPEN") || last.IsKeyword("CLOSE") || last.IsKeyword("FETCH") ||
+			last.IsKeyword("FROM") || last.IsKeyword("CURSOR") || last.IsKeyword("NEXT") ||
+			last.IsKeyword("PRIOR") || last.IsKeyword("FIRST") || last.IsKeyword("LAST") ||
+			(last.Type == SQLTokenNumber && 1 < len(tokens) &&
+				(tokens[len(tokens)-2].IsKeyword("ABSOLUTE") || tokens[len(tokens)-2].IsKeyword("RELATIVE")))) {
+			clause = SQLClauseNone
+		}
+	}
+	return clause, function
+}
+
+func collectSQLTables(tokens []SQLToken, exclude int) []SQLTableRef {
+	var tables []SQLTableRef
+	expectTable := false
+	inTables := false
+
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		switch {
+		case t.IsKeyword("FROM") || t.IsKeyword("JOIN") || t.IsKeyword("UPDATE"):
+			expectTable = true
+			inTables = true
+			continue
+		case t.IsPunct(','):
+			expectTable = inTables
+			continue
+		case t.Type == SQLTokenWord && isSQLReserved(t):
+			inTables = inTables && !(t.IsKeyword("WHERE") || t.IsKeyword("ON") || t.IsKeyword("GROUP") ||
+				t.IsKeyword("ORDER") || t.IsKeyword("HAVING") || t.IsKeyword("LIMIT") || t.IsKeyword("SET") ||
+				t.IsKeyword("USING") || t.IsKeyword("SELECT"))
+			expectTable = false
+			continue
+		}
+
+		if !expectTable || i == exclude || (t.Type != SQLTokenWord && t.Type != SQLTokenIdentifier) {
+			expectTable = false
+			continue
+		}
+		expectTable = false
+
+		// dotted names such as data.csv
+		name := t.Value()
+		for i+2 < len(tokens) && tokens[i+1].IsPunct('.') && tokens[i+1].Start == tokens[i].End &&
+			tokens[i+2].Start == tokens[i+1].End && tokens[i+2].Type == SQLTokenWord {
+			name += "." + tokens[i+2].Value()
+			i += 2
+		}
+
+		ref := SQLTableRef{Name: name}
+		j := i + 1
+		if j < len(tokens) && tokens[j].IsKeyword("AS") {
+			j++
+		}
+		if j < len(tokens) && j != exclude && !isSQLReserved(tokens[j]) &&
+			(tokens[j].Type == SQLTokenWord || tokens[j].Type == SQLTokenIdentifier) {
+			ref.Alias = tokens[j].Value()
+			i = j
+		}
+		tables = append(tables, ref)
+	}
+	return tables
+}
+
+// SQLCompleter completes a statement according to the clause at the cursor.
+// Names are supplied by the providers, any of which can be nil.
+type SQLCompleter struct {
+	Tables            func() []string
+	Columns           func(table string) []string
+	Functions         func() []string
+	AnalyticFunctions func() []string
+	Variables         func() []string
+	Cursors           func() []string
+	Keywords          func() []string
+}
+
+func sqlCandidates(names []string, formatAsIdentifier bool) CandidateList {
+	list := make(CandidateList, 0, len(names))
+	for _, n := range names {
+		if formatAsIdentifier {
+			list = append(list, IdentifierCandidate(n, true))
+			continue
+		}
+		list = append(list, Candidate{Name: []rune(n + " "), AppendSpace: true})
+	}
+	return list
+}
+
+func (c *SQLCompleter) columns(ctx *SQLContext) CandidateList {
+	if c.Columns == nil {
+		return nil
+	}
+
+	if ctx.Qualifier != "" {
+		return sqlCandidates(c.Columns(ctx.Resolve(ctx.Qualifier)), true)
+	}
+
+	var names []string
+	if len(ctx.Tables) < 1 {
+		names = c.Columns("")
+	} else {
+		exists := make(map[string]bool)
+		for _, t := range ctx.Tables {
+			for _, n := range c.Columns(t.Name) {
+				if !exists[n] {
+					exists[n] = true
+					names = append(names, n)
+				}
+			}
+		}
+	}
+	return sqlCandidates(names, true)
+}
+
+func (c *SQLCompleter) names(f func() []string, formatAsIdentifier bool) CandidateList {
+	if f == nil {
+		return nil
+	}
+	return sqlCandidates(f(), formatAsIdentifier)
+}
+
+func (c *SQLCompleter) functions(f func() []string, candidate func(string) Candidate) CandidateList {
+	if f == nil {
+		return nil
+	}
+	names := f()
+	list := make(CandidateList, 0, len(names))
+	for _, n := range names {
+		list = append(list, candidate(n))
+	}
+	return list
+}
+
+func (c *SQLCompleter) Do(line []rune, pos int, index int) (newLine CandidateList, offset int) {
+	ctx := ParseSQLContext(line, pos)
+
+	var list CandidateList
+	switch ctx.Clause {
+	case SQLClauseFrom, SQLClauseJoin:
+		list = c.names(c.Tables, true)
+	case SQLClauseCursor:
+		list = c.names(c.Cursors, true)
+	case SQLClauseSelect, SQLClauseWhere, SQLClauseGroupBy, SQLClauseOrderBy, SQLClauseFunction:
+		switch {
+		case ctx.Qualifier != "":
+			list = c.columns(&ctx)
+		case 0 < len(ctx.Prefix) && ctx.Prefix[0] == '@':
+			list = c.names(c.Variables, false)
+		default:
+			list = c.columns(&ctx)
+			list = append(list, c.functions(c.Functions, FunctionCandidate)...)
+			if ctx.Clause == SQLClauseSelect || ctx.Clause == SQLClauseOrderBy {
+				list = append(list, c.functions(c.AnalyticFunctions, AnalyticFunctionCandidate)...)
+			}
+		}
+	}
+	if ctx.Qualifier == "" && 0 < len(ctx.Prefix) && ctx.Prefix[0] != '@' && ctx.Prefix[0] != '`' {
+		list = append(list, c.names(c.Keywords, false)...)
+	}
+
+	for _, cand := range list {
+		if runes.HasPrefixFold(cand.Name, ctx.Prefix, cand.FormatAsIdentifier) {
+			newLine = append(newLine, cand)
+		}
+	}
+	newLine.Sort()
+	return newLine, len(ctx.Prefix)
+}
@@ -0,0 +1,245 @@
+package readline
+
+import (
+	"reflect"
+	"testing"
+)
+
+var tokenizeSQLTests = []struct {
+	Input  string
+	Expect []string
+}{
+	{
+		Input:  "SELECT a, b FROM t",
+		Expect: []string{"SELECT", "a", ",", "b", "FROM", "t"},
+	},
+	{
+		Input:  "SELECT `col 1` FROM `t\\`1.csv`",
+		Expect: []string{"SELECT", "`col 1`", "FROM", "`t\\`1.csv`"},
+	},
+	{
+		Input:  "SELECT 'a b', @var, @%`ENV` -- comment",
+		Expect: []string{"SELECT", "'a b'", ",", "@var", ",", "@%`ENV`"},
+	},
+	{
+		Input:  "SELECT /* c */ 1.5 FROM `unterminated",
+		Expect: []string{"SELECT", "1.5", "FROM", "`unterminated"},
+	},
+}
+
+func TestTokenizeSQL(t *testing.T) {
+	for _, v := range tokenizeSQLTests {
+		tokens := TokenizeSQL([]rune(v.Input))
+		result := make([]string, 0, len(tokens))
+		for _, tok := range tokens {
+			result = append(result, string(tok.Literal))
+		}
+		if !reflect.DeepEqual(result, v.Expect) {
+			t.Errorf("result = %q, want %q for %q", result, v.Expect, v.Input)
+		}
+	}
+}
+
+var parseSQLContextTests = []struct {
+	Input     string
+	Clause    SQLClause
+	Function  string
+	Qualifier string
+	Prefix    string
+	Tables    []SQLTableRef
+}{
+	{
+		Input:  "SELECT ",
+		Clause: SQLClauseSelect,
+	},
+	{
+		Input:  "SELECT co",
+		Clause: SQLClauseSelect,
+		Prefix: "co",
+	},
+	{
+		Input:  "SELECT a FROM ",
+		Clause: SQLClauseFrom,
+	},
+	{
+		Input:  "SELECT a FROM /path/to/fi",
+		Clause: SQLClauseFrom,
+		Prefix: "/path/to/fi",
+	},
+	{
+		Input:  "SELECT a FROM users u ",
+		Clause: SQLClauseNone,
+		Tables: []SQLTableRef{{Name: "users", Alias: "u"}},
+	},
+	{
+		Input:     "SELECT a FROM users AS u JOIN orders o ON u.",
+		Clause:    SQLClauseWhere,
+		Qualifier: "u",
+		Tables:    []SQLTableRef{{Name: "users", Alias: "u"}, {Name: "orders", Alias: "o"}},
+	},
+	{
+		Input:  "SELECT a FROM users JOIN ",
+		Clause: SQLClauseJoin,
+		Tables: []SQLTableRef{{Name: "users"}},
+	},
+	{
+		Input:  "SELECT a FROM `data.csv` WHERE a = 1 ORDER BY ",
+		Clause: SQLClauseOrderBy,
+		Tables: []SQLTableRef{{Name: "data.csv"}},
+	},
+	{
+		Input:    "SELECT COUNT(di",
+		Clause:   SQLClauseFunction,
+		Function: "COUNT",
+		Prefix:   "di",
+	},
+	{
+		Input:  "SELECT COUNT(a) AS c, ",
+		Clause: SQLClauseSelect,
+	},
+	{
+		Input:  "SELECT a FROM t WHERE a IN (",
+		Clause: SQLClauseWhere,
+		Tables: []SQLTableRef{{Name: "t"}},
+	},
+	{
+		Input:  "SELECT 'abc",
+		Clause: SQLClauseNone,
+		Prefix: "'abc",
+	},
+	{
+		Input:  "SELECT 1; FETCH NEXT ",
+		Clause: SQLClauseCursor,
+	},
+	{
+		Input:  "DISPOSE CURSOR c",
+		Clause: SQLClauseCursor,
+		Prefix: "c",
+	},
+	{
+		Input:  "SELECT @v",
+		Clause: SQLClauseSelect,
+		Prefix: "@v",
+	},
+}
+
+func TestParseSQLContext(t *testing.T) {
+	for _, v := range parseSQLContextTests {
+		line := []rune(v.Input)
+		ctx := ParseSQLContext(line, len(line))
+		if ctx.Clause != v.Clause {
+			t.Errorf("clause = %d, want %d for %q", ctx.Clause, v.Clause, v.Input)
+		}
+		if ctx.Function != v.Function {
+			t.Errorf("function = %q, want %q for %q", ctx.Function, v.Function, v.Input)
+		}
+		if ctx.Qualifier != v.Qualifier {
+			t.Errorf("qualifier = %q, want %q for %q", ctx.Qualifier, v.Qualifier, v.Input)
+		}
+		if string(ctx.Prefix) != v.Prefix {
+			t.Errorf("prefix = %q, want %q for %q", string(ctx.Prefix), v.Prefix, v.Input)
+		}
+		if !reflect.DeepEqual(ctx.Tables, v.Tables) {
+			t.Errorf("tables = %v, want %v for %q", ctx.Tables, v.Tables, v.Input)
+		}
+	}
+}
+
+var sqlCompleterTests = []struct {
+	Input  string
+	Pos    int
+	Expect []string
+	Offset int
+}{
+	{
+		Input:  "SELECT * FROM us",
+		Pos:    16,
+		Expect: []string{"users "},
+		Offset: 2,
+	},
+	{
+		Input:  "SELECT * FROM `us",
+		Pos:    17,
+		Expect: []string{"users "},
+		Offset: 3,
+	},
+	{
+		Input:  "SELECT `na` FROM users",
+		Pos:    10,
+		Expect: []string{"name "},
+		Offset: 3,
+	},
+	{
+		Input:  "SELECT u.n FROM users u",
+		Pos:    10,
+		Expect: []string{"name "},
+		Offset: 1,
+	},
+	{
+		Input:  "SELECT o. FROM users u JOIN orders o",
+		Pos:    9,
+		Expect: []string{"amount ", "id "},
+		Offset: 0,
+	},
+	{
+		Input:  "SELECT c",
+		Pos:    8,
+		Expect: []string{"COUNT() "},
+		Offset: 1,
+	},
+	{
+		Input:  "SELECT r",
+		Pos:    8,
+		Expect: []string{"ROW_NUMBER() OVER () "},
+		Offset: 1,
+	},
+	{
+		Input:  "SELECT * FROM users WHERE r",
+		Pos:    27,
+		Expect: nil,
+		Offset: 1,
+	},
+	{
+		Input:  "SELECT @",
+		Pos:    8,
+		Expect: []string{"@limit "},
+		Offset: 1,
+	},
+	{
+		Input:  "FETCH ",
+		Pos:    6,
+		Expect: []string{"cur "},
+		Offset: 0,
+	},
+}
+
+func TestSQLCompleter_Do(t *testing.T) {
+	columns := map[string][]string{
+		"users":  {"id", "name"},
+		"orders": {"id", "amount"},
+	}
+	c := &SQLCompleter{
+		Tables: func() []string { return []string{"users", "orders"} },
+		Columns: func(table string) []string {
+			return columns[table]
+		},
+		Functions:         func() []string { return []string{"COUNT"} },
+		AnalyticFunctions: func() []string { return []string{"ROW_NUMBER"} },
+		Variables:         func() []string { return []string{"@limit"} },
+		Cursors:           func() []string { return []string{"cur"} },
+	}
+
+	for _, v := range sqlCompleterTests {
+		list, offset := c.Do([]rune(v.Input), v.Pos, v.Pos)
+		var result []string
+		for _, cand := range list {
+			result = append(result, cand.StringName())
+		}
+		if !reflect.DeepEqual(result, v.Expect) {
+			t.Errorf("result = %q, want %q for %q", result, v.Expect, v.Input)
+		}
+		if offset != v.Offset {
+			t.Errorf("offset = %d, want %d for %q", offset, v.Offset, v.Input)
+		}
+	}
+}
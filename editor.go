@@ -0,0 +1,88 @@
+package readline
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// DefaultEditText writes text to a temporary file and edits it with the
+// editor specified by $VISUAL or $EDITOR.
+func DefaultEditText(text []rune) ([]rune, error) {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+		if isWindows {
+			editor = "notepad"
+		}
+	}
+	args := strings.Fields(editor)
+	if len(args) < 1 {
+		return nil, errors.New("editor is not specified")
+	}
+
+	f, err := os.CreateTemp("", "readline-*.txt")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = os.Remove(f.Name())
+	}()
+	_, err = f.WriteString(string(text) + "\n")
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(args[0], append(args[1:], f.Name())...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		return nil, err
+	}
+	return []rune(strings.TrimRight(string(data), "\r\n")), nil
+}
+
+// EditExternal edits the line with Config.FuncEditText.
+// The lines continued with a trailing backslash are edited together, and
+// they are removed from the result if they are not changed.
+// It returns CharEnter if the result is to be submitted.
+func (o *Operation) EditExternal() rune {
+	cfg := o.GetConfig()
+	prefix := o.continuousText
+	text := append(append([]rune{}, prefix...), o.buf.Runes()...)
+
+	o.buf.Clean()
+	_ = o.t.ExitRawMode()
+	edited, err := cfg.FuncEditText(text)
+	_ = o.t.EnterRawMode()
+	if err != nil {
+		o.buf.Refresh(nil)
+		o.t.Bell()
+		return 0
+	}
+
+	if runes.HasPrefix(edited, prefix) {
+		edited = edited[len(prefix):]
+	} else {
+		o.continuousBuf = nil
+		o.continuousText = nil
+	}
+	o.buf.Set(edited)
+	if cfg.EditorSubmit {
+		return CharEnter
+	}
+	return 0
+}
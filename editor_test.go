@@ -0,0 +1,21 @@
+package readline
+
+import (
+	"testing"
+)
+
+func TestDefaultEditText(t *testing.T) {
+	if isWindows {
+		t.Skip("sed is not available")
+	}
+	t.Setenv("VISUAL", "sed -i -e s/a/b/ -e $a\\x")
+
+	result, err := DefaultEditText([]rune("select a\nfrom t"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := "select b\nfrom t\nx"
+	if string(result) != expect {
+		t.Errorf("result = %q, want %q", string(result), expect)
+	}
+}
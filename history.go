@@ -3,16 +3,20 @@ package readline
 import (
 	"bufio"
 	"container/list"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"sync"
+	"time"
 )
 
 type hisItem struct {
 	Source  []rune
 	Version int64
 	Tmp     []rune
+	Time    time.Time
 }
 
 func (h *hisItem) Clean() {
@@ -40,6 +44,8 @@ func newOpHistory(cfg *Config) (o *opHistory) {
 }
 
 func (o *opHistory) Reset() {
+	o.fdLock.Lock()
+	defer o.fdLock.Unlock()
 	o.history = list.New()
 	o.current = nil
 }
@@ -86,14 +92,16 @@ func (o *opHistory) historyUpdatePath(path string) {
 		o.Push([]rune(line))
 		o.Compact()
 	}
+	o.historyVer++
+	o.Push(nil)
 	if total > o.cfg.HistoryLimit {
 		o.rewriteLocked()
 	}
-	o.historyVer++
-	o.Push(nil)
 	return
 }
 
+// Compact removes the oldest entries over the limit.
+// It is called with fdLock held, as Push is.
 func (o *opHistory) Compact() {
 	for o.history.Len() > o.cfg.HistoryLimit && o.history.Len() > 0 {
 		o.history.Remove(o.history.Front())
@@ -106,6 +114,8 @@ func (o *opHistory) Rewrite() {
 	o.rewriteLocked()
 }
 
+// rewriteLocked writes the saved entries to a new history file.
+// The last element, the line being edited, is not written.
 func (o *opHistory) rewriteLocked() {
 	if o.cfg.HistoryFile == "" {
 		return
@@ -118,7 +128,7 @@ func (o *opHistory) rewriteLocked() {
 	}
 
 	buf := bufio.NewWriter(fd)
-	for elem := o.history.Front(); elem != nil; elem = elem.Next() {
+	for elem := o.history.Front(); elem != nil && elem != o.history.Back(); elem = elem.Next() {
 		buf.WriteString(string(elem.Value.(*hisItem).Source) + "\n")
 	}
 	buf.Flush()
@@ -145,6 +155,8 @@ func (o *opHistory) Close() {
 }
 
 func (o *opHistory) FindBck(isNewSearch bool, rs []rune, start int) (int, *list.Element) {
+	o.fdLock.Lock()
+	defer o.fdLock.Unlock()
 	for elem := o.current; elem != nil; elem = elem.Prev() {
 		item := o.showItem(elem.Value)
 		if isNewSearch {
@@ -165,6 +177,8 @@ func (o *opHistory) FindBck(isNewSearch bool, rs []rune, start int) (int, *list.
 }
 
 func (o *opHistory) FindFwd(isNewSearch bool, rs []rune, start int) (int, *list.Element) {
+	o.fdLock.Lock()
+	defer o.fdLock.Unlock()
 	for elem := o.current; elem != nil; elem = elem.Next() {
 		item := o.showItem(elem.Value)
 		if isNewSearch {
@@ -200,7 +214,24 @@ func (o *opHistory) showItem(obj interface{}) []rune {
 	return item.Source
 }
 
+// Current returns the element of the line being shown.
+func (o *opHistory) Current() *list.Element {
+	o.fdLock.Lock()
+	defer o.fdLock.Unlock()
+	return o.current
+}
+
+// MoveTo makes elem the current element and returns the line of it.
+func (o *opHistory) MoveTo(elem *list.Element) []rune {
+	o.fdLock.Lock()
+	defer o.fdLock.Unlock()
+	o.current = elem
+	return runes.Copy(o.showItem(elem.Value))
+}
+
 func (o *opHistory) Prev() []rune {
+	o.fdLock.Lock()
+	defer o.fdLock.Unlock()
 	if o.current == nil {
 		return nil
 	}
@@ -213,6 +244,8 @@ func (o *opHistory) Prev() []rune {
 }
 
 func (o *opHistory) Next() ([]rune, bool) {
+	o.fdLock.Lock()
+	defer o.fdLock.Unlock()
 	if o.current == nil {
 		return nil, false
 	}
@@ -225,13 +258,75 @@ func (o *opHistory) Next() ([]rune, bool) {
 	return runes.Copy(o.showItem(current.Value)), true
 }
 
+// FindPrefix moves to the previous entry, or the next entry if forward is
+// true, that starts with prefix and differs from line.
+func (o *opHistory) FindPrefix(prefix []rune, line []rune, forward bool) ([]rune, bool) {
+	o.fdLock.Lock()
+	defer o.fdLock.Unlock()
+	if o.current == nil {
+		return nil, false
+	}
+	for elem := o.step(o.current, forward); elem != nil; elem = o.step(elem, forward) {
+		item := o.showItem(elem.Value)
+		if runes.Equal(item, line) {
+			continue
+		}
+		if o.cfg.HistorySearchFold && !runes.HasPrefixFold(item, prefix, false) ||
+			!o.cfg.HistorySearchFold && !runes.HasPrefix(item, prefix) {
+			continue
+		}
+		o.current = elem
+		return runes.Copy(item), true
+	}
+	return nil, false
+}
+
+func (o *opHistory) step(elem *list.Element, forward bool) *list.Element {
+	if forward {
+		return elem.Next()
+	}
+	return elem.Prev()
+}
+
+// Entries returns the entries saved in the history from the oldest one.
+func (o *opHistory) Entries() [][]rune {
+	o.fdLock.Lock()
+	defer o.fdLock.Unlock()
+	entries := make([][]rune, 0, o.history.Len())
+	if o.history.Back() == nil {
+		return entries
+	}
+	for elem := o.history.Front(); elem != o.history.Back(); elem = elem.Next() {
+		entries = append(entries, runes.Copy(elem.Value.(*hisItem).Source))
+	}
+	return entries
+}
+
+// Recent returns the nth most recent entry saved in the history.
+func (o *opHistory) Recent(n int) ([]rune, bool) {
+	o.fdLock.Lock()
+	defer o.fdLock.Unlock()
+	elem := o.history.Back()
+	for i := 0; i < n && elem != nil; i++ {
+		elem = elem.Prev()
+	}
+	if elem == nil {
+		return nil, false
+	}
+	return runes.Copy(elem.Value.(*hisItem).Source), true
+}
+
 // Disable the current history
 func (o *opHistory) Disable() {
+	o.fdLock.Lock()
+	defer o.fdLock.Unlock()
 	o.enable = false
 }
 
 // Enable the current history
 func (o *opHistory) Enable() {
+	o.fdLock.Lock()
+	defer o.fdLock.Unlock()
 	o.enable = true
 }
 
@@ -244,6 +339,8 @@ func (o *opHistory) debug() {
 
 // save history
 func (o *opHistory) New(current []rune) (err error) {
+	o.fdLock.Lock()
+	defer o.fdLock.Unlock()
 
 	// history deactivated
 	if !o.enable {
@@ -285,7 +382,7 @@ func (o *opHistory) New(current []rune) (err error) {
 	}
 
 	// err only can be a IO error, just report
-	err = o.Update(current, true)
+	err = o.updateLocked(current, true)
 
 	// push a new one to commit current command
 	o.historyVer++
@@ -294,6 +391,8 @@ func (o *opHistory) New(current []rune) (err error) {
 }
 
 func (o *opHistory) Revert() {
+	o.fdLock.Lock()
+	defer o.fdLock.Unlock()
 	o.historyVer++
 	o.current = o.history.Back()
 }
@@ -301,6 +400,10 @@ func (o *opHistory) Revert() {
 func (o *opHistory) Update(s []rune, commit bool) (err error) {
 	o.fdLock.Lock()
 	defer o.fdLock.Unlock()
+	return o.updateLocked(s, commit)
+}
+
+func (o *opHistory) updateLocked(s []rune, commit bool) (err error) {
 	s = runes.Copy(s)
 	if o.current == nil {
 		o.Push(s)
@@ -311,6 +414,7 @@ func (o *opHistory) Update(s []rune, commit bool) (err error) {
 	r.Version = o.historyVer
 	if commit {
 		r.Source = s
+		r.Time = time.Now()
 		if o.fd != nil {
 			// just report the error
 			_, err = o.fd.Write([]byte(string(r.Source) + "\n"))
@@ -323,8 +427,227 @@ func (o *opHistory) Update(s []rune, commit bool) (err error) {
 	return
 }
 
+// saved returns the element of the nth entry saved in the history.
+// The last element is the line being edited, which is not saved yet.
+func (o *opHistory) saved(n int) *list.Element {
+	if n < 1 {
+		return nil
+	}
+	elem := o.history.Front()
+	for i := 1; i < n && elem != nil; i++ {
+		elem = elem.Next()
+	}
+	if elem == o.history.Back() {
+		return nil
+	}
+	return elem
+}
+
+// Delete removes the nth entry and rewrites the history file.
+func (o *opHistory) Delete(n int) error {
+	o.fdLock.Lock()
+	defer o.fdLock.Unlock()
+	elem := o.saved(n)
+	if elem == nil {
+		return ErrHistoryOutOfRange
+	}
+	if o.current == elem {
+		o.current = o.history.Back()
+	}
+	o.history.Remove(elem)
+	o.rewriteLocked()
+	return nil
+}
+
+// Replace replaces the nth entry with s and rewrites the history file.
+func (o *opHistory) Replace(n int, s []rune) error {
+	o.fdLock.Lock()
+	defer o.fdLock.Unlock()
+	elem := o.saved(n)
+	if elem == nil {
+		return ErrHistoryOutOfRange
+	}
+	item := elem.Value.(*hisItem)
+	item.Source = runes.Copy(s)
+	item.Tmp = runes.Copy(s)
+	o.rewriteLocked()
+	return nil
+}
+
+// Clear removes all the entries and rewrites the history file.
+// The line being edited is kept as the last element.
+func (o *opHistory) Clear() {
+	o.fdLock.Lock()
+	defer o.fdLock.Unlock()
+	back := o.history.Back()
+	if back == nil {
+		o.Push(nil)
+		back = o.history.Back()
+	}
+	for o.history.Front() != back {
+		o.history.Remove(o.history.Front())
+	}
+	o.current = back
+	o.historyVer++
+	o.rewriteLocked()
+}
+
+// Export writes the entries to w in the format of the history file.
+func (o *opHistory) Export(w io.Writer) error {
+	buf := bufio.NewWriter(w)
+	for _, e := range o.Entries() {
+		if _, err := buf.WriteString(string(e) + "\n"); err != nil {
+			return err
+		}
+	}
+	return buf.Flush()
+}
+
+// Import appends the entries read from r in the format of the history file,
+// and rewrites the history file.
+func (o *opHistory) Import(r io.Reader) error {
+	var lines [][]rune
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if 0 < len(line) {
+			lines = append(lines, []rune(line))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	o.fdLock.Lock()
+	defer o.fdLock.Unlock()
+	if o.history.Back() == nil {
+		o.Push(nil)
+	}
+	back := o.history.Back()
+	for _, line := range lines {
+		o.history.InsertBefore(&hisItem{Source: line}, back)
+	}
+	o.historyVer++
+	o.Compact()
+	o.rewriteLocked()
+	return nil
+}
+
 func (o *opHistory) Push(s []rune) {
 	s = runes.Copy(s)
 	elem := o.history.PushBack(&hisItem{Source: s})
 	o.current = elem
 }
+
+var ErrHistoryOutOfRange = errors.New("history index out of range")
+
+// HistoryNamespace defines a history separated from the default one,
+// such as the history of the answers to confirmation prompts.
+type HistoryNamespace struct {
+	// File persists the history. If empty, the history is kept in memory.
+	File string
+	// Limit is the maximum number of the entries.
+	// If 0, Config.HistoryLimit is used.
+	Limit int
+	// If Disable is true, no lines are saved to the history.
+	Disable bool
+}
+
+// HistoryEntry is an entry of the history.
+type HistoryEntry struct {
+	// Index is the 1-based position of the entry, which is also used by
+	// the history expansion "!n".
+	Index int
+	Line  string
+	// Time is when the entry is saved. It is zero for the entries loaded
+	// from the history file.
+	Time time.Time
+}
+
+// HistoryIterator iterates over the history entries from the oldest one.
+//
+//	it := rl.History().Iterator()
+//	for it.Next() {
+//		e := it.Entry()
+//		fmt.Println(e.Index, e.Line)
+//	}
+type HistoryIterator struct {
+	entries []HistoryEntry
+	pos     int
+}
+
+// Next advances the iterator to the next entry, and reports whether there
+// is the entry.
+func (it *HistoryIterator) Next() bool {
+	if len(it.entries) <= it.pos {
+		return false
+	}
+	it.pos++
+	return true
+}
+
+// Entry returns the current entry.
+func (it *HistoryIterator) Entry() HistoryEntry {
+	return it.entries[it.pos-1]
+}
+
+// History lists and edits the history of an Instance.
+// The changes are written to Config.HistoryFile.
+type History struct {
+	op *Operation
+}
+
+// history returns the history of the current namespace.
+func (h *History) history() *opHistory {
+	h.op.m.Lock()
+	defer h.op.m.Unlock()
+	return h.op.history
+}
+
+// Iterator returns an iterator over the snapshot of the entries.
+func (h *History) Iterator() *HistoryIterator {
+	o := h.history()
+	o.fdLock.Lock()
+	defer o.fdLock.Unlock()
+
+	it := &HistoryIterator{}
+	for elem := o.history.Front(); elem != nil && elem != o.history.Back(); elem = elem.Next() {
+		item := elem.Value.(*hisItem)
+		it.entries = append(it.entries, HistoryEntry{
+			Index: len(it.entries) + 1,
+			Line:  string(item.Source),
+			Time:  item.Time,
+		})
+	}
+	return it
+}
+
+// Len returns the number of the entries.
+func (h *History) Len() int {
+	return len(h.history().Entries())
+}
+
+// Delete removes the entry at the 1-based index i.
+func (h *History) Delete(i int) error {
+	return h.history().Delete(i)
+}
+
+// Replace replaces the entry at the 1-based index i with s.
+func (h *History) Replace(i int, s string) error {
+	return h.history().Replace(i, []rune(s))
+}
+
+// Clear removes all the entries.
+func (h *History) Clear() {
+	h.history().Clear()
+}
+
+// Export writes the entries to w, one entry per line.
+func (h *History) Export(w io.Writer) error {
+	return h.history().Export(w)
+}
+
+// Import appends the entries read from r, one entry per line.
+func (h *History) Import(r io.Reader) error {
+	return h.history().Import(r)
+}
@@ -0,0 +1,268 @@
+package readline
+
+import (
+	"fmt"
+	"strconv"
+	"unicode"
+)
+
+// ExpandHistory expands the history references in line in the manner of
+// bash, and reports whether line is changed.
+// entries are the history entries from the oldest one, and split splits
+// an entry into the words selected by the word designators.
+//
+// The supported events are !!, !n, !-n, !string and !?string?, followed by
+// an optional word designator :n, :n-m, :^, :$ or :*. !^, !$ and !* are the
+// shorthands of !!:^, !!:$ and !!:*. ^old^new at the beginning of line
+// replaces old with new in the previous entry.
+// References in quoted strings and identifiers are not expanded, and "!"
+// escaped with a backslash or followed by a space, "=" or "(" is left as it
+// is. The backslashes are removed by UnescapeHistory.
+func ExpandHistory(line []rune, entries [][]rune, split func([]rune) [][]rune) ([]rune, bool, error) {
+	if 0 < len(line) && line[0] == '^' {
+		ret, err := quickSubstitute(line, entries)
+		return ret, err == nil, err
+	}
+
+	ret := make([]rune, 0, len(line))
+	changed := false
+	var quote rune
+	for i := 0; i < len(line); i++ {
+		r := line[i]
+		if quote != 0 {
+			ret = append(ret, r)
+			if r == '\\' && i+1 < len(line) {
+				i++
+				ret = append(ret, line[i])
+			} else if r == quote {
+				quote = 0
+			}
+			continue
+		}
+
+		switch {
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '\\' && i+1 < len(line) && line[i+1] == '!':
+			ret = append(ret, r)
+			i++
+			r = '!'
+		case r == '!' && i+1 < len(line) && !isHistoryEventEnd(line[i+1]):
+			words, n, err := expandHistoryEvent(line[i:], entries, split)
+			if err != nil {
+				return nil, false, err
+			}
+			ret = append(ret, words...)
+			i += n - 1
+			changed = true
+			continue
+		}
+		ret = append(ret, r)
+	}
+	return ret, changed, nil
+}
+
+// UnescapeHistory removes the backslashes escaping "!" outside quotes.
+func UnescapeHistory(line []rune) []rune {
+	ret := make([]rune, 0, len(line))
+	var quote rune
+	for i := 0; i < len(line); i++ {
+		r := line[i]
+		switch {
+		case quote != 0:
+			if r == '\\' && i+1 < len(line) {
+				ret = append(ret, r)
+				i++
+				r = line[i]
+			} else if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '\\' && i+1 < len(line) && line[i+1] == '!':
+			i++
+			r = '!'
+		}
+		ret = append(ret, r)
+	}
+	return ret
+}
+
+func isHistoryEventEnd(r rune) bool {
+	return unicode.IsSpace(r) || r == '=' || r == '('
+}
+
+// expandHistoryEvent expands the reference at the beginning of s, and
+// returns the expanded text and the length of the reference.
+func expandHistoryEvent(s []rune, entries [][]rune, split func([]rune) [][]rune) ([]rune, int, error) {
+	recent := func(n int) ([]rune, bool) {
+		if n < 1 || len(entries) < n {
+			return nil, false
+		}
+		return entries[len(entries)-n], true
+	}
+	search := func(text []rune, prefix bool) ([]rune, bool) {
+		for i := len(entries) - 1; 0 <= i; i-- {
+			if prefix && runes.HasPrefix(entries[i], text) ||
+				!prefix && -1 < runes.IndexAll(entries[i], text) {
+				return entries[i], true
+			}
+		}
+		return nil, false
+	}
+
+	var entry []rune
+	var found bool
+	designator := ""
+	i := 1
+	switch {
+	case s[i] == '!':
+		i++
+		entry, found = recent(1)
+	case s[i] == '^' || s[i] == '$' || s[i] == '*':
+		designator = string(s[i])
+		i++
+		entry, found = recent(1)
+	case s[i] == '-' || unicode.IsDigit(s[i]):
+		j := i + 1
+		for j < len(s) && unicode.IsDigit(s[j]) {
+			j++
+		}
+		n, err := strconv.Atoi(string(s[i:j]))
+		if err != nil {
+			return nil, 0, fmt.Errorf("%s: event not found", string(s[:j]))
+		}
+		i = j
+		if n < 0 {
+			entry, found = recent(-n)
+		} else if 0 < n && n <= len(entries) {
+			entry, found = entries[n-1], true
+		}
+	case s[i] == '?':
+		j := i + 1
+		for j < len(s) && s[j] != '?' {
+			j++
+		}
+		entry, found = search(s[i+1:j], false)
+		i = j
+		if i < len(s) {
+			i++
+		}
+	default:
+		j := i
+		for j < len(s) && !unicode.IsSpace(s[j]) && s[j] != ':' && s[j] != ';' {
+			j++
+		}
+		entry, found = search(s[i:j], true)
+		i = j
+	}
+	if !found {
+		return nil, 0, fmt.Errorf("%s: event not found", string(s[:i]))
+	}
+
+	if designator == "" && i+1 < len(s) && s[i] == ':' {
+		j := i + 1
+		for j < len(s) && (unicode.IsDigit(s[j]) || s[j] == '-' || s[j] == '^' || s[j] == '$' || s[j] == '*') {
+			j++
+		}
+		designator = string(s[i+1 : j])
+		if designator == "" {
+			return nil, 0, fmt.Errorf("%s: bad word specifier", string(s[:j]))
+		}
+		i = j
+	}
+	if designator == "" {
+		return entry, i, nil
+	}
+
+	words, ok := selectHistoryWords(split(entry), designator)
+	if !ok {
+		return nil, 0, fmt.Errorf("%s: bad word specifier", string(s[:i]))
+	}
+	return words, i, nil
+}
+
+// selectHistoryWords joins the words selected by the word designator.
+func selectHistoryWords(words [][]rune, designator string) ([]rune, bool) {
+	index := func(s string) (int, bool) {
+		switch s {
+		case "^":
+			return 1, true
+		case "$":
+			return len(words) - 1, true
+		}
+		n, err := strconv.Atoi(s)
+		return n, err == nil
+	}
+
+	var start, end int
+	switch designator {
+	case "*":
+		start, end = 1, len(words)-1
+		if end < start {
+			return []rune{}, true
+		}
+	default:
+		from, to := designator, designator
+		for i := 1; i < len(designator); i++ {
+			if designator[i] == '-' {
+				from, to = designator[:i], designator[i+1:]
+				break
+			}
+		}
+		var ok1, ok2 bool
+		start, ok1 = index(from)
+		end, ok2 = index(to)
+		if !ok1 || !ok2 {
+			return nil, false
+		}
+	}
+	if start < 0 || end < start || len(words) <= end {
+		return nil, false
+	}
+
+	ret := []rune{}
+	for i := start; i <= end; i++ {
+		if start < i {
+			ret = append(ret, ' ')
+		}
+		ret = append(ret, words[i]...)
+	}
+	return ret, true
+}
+
+// quickSubstitute replaces old with new in the previous entry for the
+// line "^old^new^".
+func quickSubstitute(line []rune, entries [][]rune) ([]rune, error) {
+	if len(entries) < 1 {
+		return nil, fmt.Errorf("%s: event not found", string(line))
+	}
+
+	parts := [][]rune{{}}
+	for i := 1; i < len(line); i++ {
+		if line[i] == '^' && len(parts) < 3 {
+			parts = append(parts, []rune{})
+			continue
+		}
+		parts[len(parts)-1] = append(parts[len(parts)-1], line[i])
+	}
+	old, repl, rest := parts[0], []rune{}, []rune{}
+	if 1 < len(parts) {
+		repl = parts[1]
+	}
+	if 2 < len(parts) {
+		rest = parts[2]
+	}
+
+	prev := entries[len(entries)-1]
+	idx := runes.IndexAll(prev, old)
+	if len(old) < 1 || idx < 0 {
+		return nil, fmt.Errorf("%s: substitution failed", string(line))
+	}
+	ret := make([]rune, 0, len(prev)-len(old)+len(repl)+len(rest))
+	ret = append(ret, prev[:idx]...)
+	ret = append(ret, repl...)
+	ret = append(ret, prev[idx+len(old):]...)
+	ret = append(ret, rest...)
+	return ret, nil
+}
@@ -0,0 +1,63 @@
+package readline
+
+import (
+	"testing"
+)
+
+var expandHistoryEntries = [][]rune{
+	[]rune("select * from users.csv"),
+	[]rune("select id, name from `my items.csv`"),
+	[]rune("update t set a = 1"),
+}
+
+var expandHistoryTests = []struct {
+	Line    string
+	Expect  string
+	Changed bool
+	Error   string
+}{
+	{Line: "!!", Expect: "update t set a = 1", Changed: true},
+	{Line: "!-3;", Expect: "select * from users.csv;", Changed: true},
+	{Line: "!2", Expect: "select id, name from `my items.csv`", Changed: true},
+	{Line: "!sel", Expect: "select id, name from `my items.csv`", Changed: true},
+	{Line: "!?users?", Expect: "select * from users.csv", Changed: true},
+	{Line: "select * from !sel:$", Expect: "select * from `my items.csv`", Changed: true},
+	{Line: "select !2:1-2 from t", Expect: "select id name from t", Changed: true},
+	{Line: "delete from !1:^", Expect: "delete from *", Changed: true},
+	{Line: "echo !$ !*", Expect: "echo 1 t set a 1", Changed: true},
+	{Line: "^a =^b =", Expect: "update t set b = 1", Changed: true},
+	{Line: "^1^2^ where b", Expect: "update t set a = 2 where b", Changed: true},
+	{Line: "select 'a!!' from t where a != 1", Expect: "select 'a!!' from t where a != 1"},
+	{Line: "select `!!` from t where !(a)", Expect: "select `!!` from t where !(a)"},
+	{Line: "select \\!!", Expect: "select \\!!"},
+	{Line: "!delete", Error: "!delete: event not found"},
+	{Line: "!!:9", Error: "!!:9: bad word specifier"},
+	{Line: "^x^y", Error: "^x^y: substitution failed"},
+}
+
+func TestExpandHistory(t *testing.T) {
+	for _, v := range expandHistoryTests {
+		result, changed, err := ExpandHistory([]rune(v.Line), expandHistoryEntries, SplitSQLArgs)
+		if err != nil {
+			if err.Error() != v.Error {
+				t.Errorf("error = %q, want %q for %q", err.Error(), v.Error, v.Line)
+			}
+			continue
+		}
+		if v.Error != "" {
+			t.Errorf("no error, want %q for %q", v.Error, v.Line)
+			continue
+		}
+		if string(result) != v.Expect || changed != v.Changed {
+			t.Errorf("result = %q (%t), want %q (%t) for %q", string(result), changed, v.Expect, v.Changed, v.Line)
+		}
+	}
+}
+
+func TestUnescapeHistory(t *testing.T) {
+	line := "select \\!a, '\\!b' from t"
+	expect := "select !a, '\\!b' from t"
+	if result := string(UnescapeHistory([]rune(line))); result != expect {
+		t.Errorf("result = %q, want %q for %q", result, expect, line)
+	}
+}
@@ -0,0 +1,166 @@
+package readline
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOpHistory_FindPrefix(t *testing.T) {
+	h := newOpHistory(&Config{HistoryLimit: 10})
+	for _, s := range []string{"select 1", "update t", "select 2", "select 2", "SELECT 3"} {
+		h.Push([]rune(s))
+	}
+	h.historyVer++
+	h.Push(nil)
+	h.Update([]rune("sel"), false)
+
+	tests := []struct {
+		Prefix  string
+		Line    string
+		Forward bool
+		Expect  string
+		OK      bool
+	}{
+		{Prefix: "sel", Line: "sel", Expect: "select 2", OK: true},
+		{Prefix: "sel", Line: "select 2", Expect: "select 1", OK: true},
+		{Prefix: "sel", Line: "select 1", OK: false},
+		{Prefix: "sel", Line: "select 1", Forward: true, Expect: "select 2", OK: true},
+		{Prefix: "sel", Line: "select 2", Forward: true, Expect: "sel", OK: true},
+	}
+	for _, v := range tests {
+		result, ok := h.FindPrefix([]rune(v.Prefix), []rune(v.Line), v.Forward)
+		if string(result) != v.Expect || ok != v.OK {
+			t.Errorf("result = %q (%t), want %q (%t) for %q", string(result), ok, v.Expect, v.OK, v.Line)
+		}
+	}
+
+	h.cfg.HistorySearchFold = true
+	if result, _ := h.FindPrefix([]rune("sel"), []rune("sel"), false); string(result) != "SELECT 3" {
+		t.Errorf("result = %q, want %q", string(result), "SELECT 3")
+	}
+}
+
+func TestHistory(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &Config{HistoryFile: filepath.Join(dir, "history"), HistoryLimit: 10}
+	op := &Operation{history: newOpHistory(cfg)}
+	op.history.historyUpdatePath(cfg.HistoryFile)
+	for _, s := range []string{"select 1", "select 2", "select 3"} {
+		if err := op.history.New([]rune(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := &History{op: op}
+
+	lines := func() string {
+		var ret []string
+		it := h.Iterator()
+		for it.Next() {
+			e := it.Entry()
+			ret = append(ret, fmt.Sprintf("%d:%s", e.Index, e.Line))
+		}
+		return strings.Join(ret, ",")
+	}
+	file := func() string {
+		b, _ := os.ReadFile(cfg.HistoryFile)
+		return string(b)
+	}
+
+	if err := h.Delete(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Replace(2, "select 4"); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Delete(3); err != ErrHistoryOutOfRange {
+		t.Errorf("error = %v, want %v", err, ErrHistoryOutOfRange)
+	}
+	if result := lines(); result != "1:select 1,2:select 4" {
+		t.Errorf("entries = %q, want %q", result, "1:select 1,2:select 4")
+	}
+	if result := file(); result != "select 1\nselect 4\n" {
+		t.Errorf("file = %q, want %q", result, "select 1\nselect 4\n")
+	}
+
+	buf := &bytes.Buffer{}
+	if err := h.Export(buf); err != nil {
+		t.Fatal(err)
+	}
+	h.Clear()
+	if h.Len() != 0 || file() != "" {
+		t.Errorf("history is not cleared: %d entries, file %q", h.Len(), file())
+	}
+	if op.history.history.Len() != 1 || op.history.current != op.history.history.Back() {
+		t.Errorf("the line being edited is not kept")
+	}
+	if err := h.Import(strings.NewReader(buf.String() + "\nselect 5\n")); err != nil {
+		t.Fatal(err)
+	}
+	if result := lines(); result != "1:select 1,2:select 4,3:select 5" {
+		t.Errorf("entries = %q, want %q", result, "1:select 1,2:select 4,3:select 5")
+	}
+	if result := file(); result != "select 1\nselect 4\nselect 5\n" {
+		t.Errorf("file = %q, want %q", result, "select 1\nselect 4\nselect 5\n")
+	}
+	if result := string(op.history.Prev()); result != "select 5" {
+		t.Errorf("prev = %q, want %q", result, "select 5")
+	}
+	op.history.Close()
+}
+
+func TestOperation_SetHistoryNamespace(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &Config{
+		HistoryLimit: 10,
+		FuncGetWidth: func() int { return 80 },
+		HistoryNamespaces: map[string]HistoryNamespace{
+			"confirm": {File: filepath.Join(dir, "confirm"), Limit: 2},
+			"secret":  {Disable: true},
+		},
+	}
+	cfg.opHistory = newOpHistory(cfg)
+	op := &Operation{cfg: cfg, buf: new(RuneBuffer), history: cfg.opHistory}
+
+	op.history.New([]rune("select 1"))
+	if err := op.SetHistoryNamespace("confirm"); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"y", "n", "y"} {
+		op.history.New([]rune(s))
+	}
+	if result := op.history.Entries(); len(result) != 2 || string(result[1]) != "y" {
+		t.Errorf("entries = %q, want the last 2 answers", result)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, "confirm")); string(b) != "y\nn\ny\n" {
+		t.Errorf("file = %q, want %q", string(b), "y\nn\ny\n")
+	}
+
+	if err := op.SetHistoryNamespace("secret"); err != nil {
+		t.Fatal(err)
+	}
+	op.history.New([]rune("password"))
+	if result := op.history.Entries(); len(result) != 0 {
+		t.Errorf("entries = %q, want no entries", result)
+	}
+
+	if err := op.SetHistoryNamespace("unknown"); err == nil {
+		t.Errorf("no error for an unknown namespace")
+	}
+	if err := op.SetHistoryNamespace(""); err != nil {
+		t.Fatal(err)
+	}
+	if result := op.history.Entries(); len(result) != 1 || string(result[0]) != "select 1" {
+		t.Errorf("entries = %q, want %q", result, "select 1")
+	}
+	if err := op.SetHistoryNamespace("confirm"); err != nil {
+		t.Fatal(err)
+	}
+	if result := op.history.Entries(); len(result) != 2 {
+		t.Errorf("entries = %q, want the history to be kept", result)
+	}
+	op.Close()
+}
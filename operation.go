@@ -1,15 +1,25 @@
 package readline
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"io"
+	"math"
 	"sync"
 )
 
 var (
 	ErrInterrupt = errors.New("Interrupt")
+
+	// errCanceled is sent by ioloop when the line is discarded by
+	// RunesContext.
+	errCanceled = errors.New("canceled")
 )
 
+// charCancel is read instead of a key when the read is canceled.
+const charCancel rune = math.MinInt32
+
 type InterruptError struct {
 	Line []rune
 }
@@ -27,13 +37,40 @@ type Operation struct {
 	errchan chan error
 	w       io.Writer
 
-	continuousBuf []rune
+	continuousBuf  []rune
+	continuousText []rune
+
+	prompt string
+
+	yankArg *yankArgState
+
+	// hint is shown below the input by SetHint.
+	hint string
+	// invalid is the line rejected by Config.Validator, and invalidMsg is
+	// the error shown until the line is edited.
+	invalid    []rune
+	invalidMsg string
+
+	cancelM    sync.Mutex
+	canceled   bool
+	cancelchan chan struct{}
+	// aborted is true if the last read is canceled in the middle of the
+	// line, and the terminal does not wait for KickRead.
+	aborted bool
+
+	// paused is true if the terminal may wait for Resume.
+	paused bool
 
 	history *opHistory
+
+	namespace  string
+	namespaces map[string]*historyNamespace
+
 	*opSearch
 	*opCompleter
 	*opPassword
 	*opVim
+	*opSnippet
 }
 
 func (o *Operation) SetBuffer(what string) {
@@ -75,6 +112,7 @@ func NewOperation(t *Terminal, cfg *Config) *Operation {
 		buf:           NewRuneBuffer(t, cfg.Prompt, cfg, width),
 		outchan:       make(chan []rune),
 		errchan:       make(chan error, 1),
+		cancelchan:    make(chan struct{}, 1),
 		continuousBuf: nil,
 	}
 	op.w = op.buf.w
@@ -82,6 +120,7 @@ func NewOperation(t *Terminal, cfg *Config) *Operation {
 	op.opVim = newVimMode(op)
 	op.opCompleter = newOpCompleter(op.buf.w, op, width)
 	op.opPassword = newOpPassword(op)
+	op.opSnippet = newOpSnippet(op)
 	op.cfg.FuncOnWidthChanged(func() {
 		newWidth := cfg.FuncGetWidth()
 		op.opCompleter.OnWidthChange(newWidth)
@@ -92,7 +131,59 @@ func NewOperation(t *Terminal, cfg *Config) *Operation {
 	return op
 }
 
+// readRune reads the next key.
+// In vim mode, the keys replayed by a macro or "." are read first.
+func (o *Operation) readRune() rune {
+	if o.IsEnableVimMode() {
+		return o.ReadVimRune(o.readKey)
+	}
+	return o.readKey()
+}
+
+// pauseAfterNext stops the terminal reading stdin after the next key until
+// the following key is requested, so that a program run by that key, such
+// as an external editor, receives all the input.
+func (o *Operation) pauseAfterNext() {
+	o.resume()
+	o.t.PauseAfterNext()
+	o.paused = true
+}
+
+func (o *Operation) resume() {
+	if o.paused {
+		o.t.Resume()
+		o.paused = false
+	}
+}
+
+// isCanceled reports whether the read is canceled by RunesContext.
+// The keys read after the cancellation are charCancel, so the commands
+// reading further keys are abandoned.
+func (o *Operation) isCanceled() bool {
+	o.cancelM.Lock()
+	defer o.cancelM.Unlock()
+	return o.canceled
+}
+
+// readKey reads a key from the terminal. It returns charCancel while the
+// read is canceled by RunesContext.
+func (o *Operation) readKey() rune {
+	o.resume()
+	for {
+		if o.isCanceled() {
+			return charCancel
+		}
+		if r, ok := o.t.readRuneOr(o.cancelchan); ok {
+			return r
+		}
+	}
+}
+
 func (o *Operation) SetPrompt(s string) {
+	o.prompt = s
+	if o.opVim != nil {
+		s = o.VimPrompt(s)
+	}
 	o.buf.SetPrompt(s)
 }
 
@@ -111,7 +202,16 @@ func (o *Operation) ioloop() {
 	for {
 		keepInSearchMode := false
 		keepInCompleteMode := false
-		r := o.t.ReadRune()
+		if o.IsEnableVimMode() && o.vimMode == VIM_NORMAL {
+			// v runs the external editor
+			o.pauseAfterNext()
+		}
+		r := o.readRune()
+
+		if r == charCancel {
+			o.abortLine()
+			continue
+		}
 
 		if o.GetConfig().FuncFilterInputRune != nil {
 			var process bool
@@ -138,6 +238,9 @@ func (o *Operation) ioloop() {
 			}
 		}
 		isUpdateHistory := true
+		isUpdateHint := true
+		isSubmitted := false
+		var submitted []rune
 
 		if o.IsInCompleteSelectMode() {
 			keepInCompleteMode = o.HandleCompleteSelect(r)
@@ -148,7 +251,7 @@ func (o *Operation) ioloop() {
 			o.buf.Refresh(nil)
 			switch r {
 			case CharEnter, CharCtrlJ:
-				o.history.Update(o.buf.Runes(), false)
+				o.getHistory().Update(o.buf.Runes(), false)
 				fallthrough
 			case CharInterrupt:
 				o.t.KickRead()
@@ -159,12 +262,45 @@ func (o *Operation) ioloop() {
 		}
 
 		if o.IsEnableVimMode() {
-			r = o.HandleVim(r, o.t.ReadRune)
+			// a vim command canceled while reading its keys returns 0,
+			// and the line is aborted by the next read
+			r = o.HandleVim(r, o.readRune)
+			if r == 0 {
+				continue
+			}
+		}
+
+		n := 1
+		if r == MetaMinus || IsMetaDigit(r) || (r == CharCtrlU && o.GetConfig().UseUniversalArgument) {
+			r, n = o.readArgument(r)
+			if r == charCancel {
+				o.abortLine()
+				continue
+			}
+			if rev, ok := reverseKeys[r]; ok && n < 0 {
+				r, n = rev, -n
+			}
+		}
+
+		if r == CharCtrlX {
+			// Ctrl+X Ctrl+E runs the external editor
+			o.pauseAfterNext()
+			next := o.readRune()
+			if next == charCancel {
+				o.abortLine()
+				continue
+			}
+			r = o.HandleCtrlX(next)
 			if r == 0 {
 				continue
 			}
 		}
 
+		inSnippetMode := o.IsSnippetMode()
+		if inSnippetMode && o.HandleSnippet(r) {
+			continue
+		}
+
 		switch r {
 		case CharBell:
 			if o.IsSearchMode() {
@@ -206,16 +342,34 @@ func (o *Operation) ioloop() {
 			}
 			keepInSearchMode = true
 		case CharKill:
+			if n < 0 {
+				o.buf.KillFront()
+				break
+			}
 			o.buf.Kill()
 			keepInCompleteMode = true
 		case MetaForward:
-			o.buf.MoveToNextWord()
+			for i := 0; i < n; i++ {
+				o.buf.MoveToNextWord()
+			}
 		case CharTranspose:
 			o.buf.Transpose()
+		case MetaTranspose:
+			if !o.buf.TransposeWords() {
+				o.t.Bell()
+			}
+		case MetaUpcase:
+			o.buf.UpcaseWord()
+		case MetaDowncase:
+			o.buf.DowncaseWord()
+		case MetaCapitalize:
+			o.buf.CapitalizeWord()
 		case MetaBackward:
-			o.buf.MoveToPrevWord()
+			for i := 0; i < n; i++ {
+				o.buf.MoveToPrevWord()
+			}
 		case MetaDelete:
-			o.buf.DeleteWord()
+			o.buf.DeleteWords(n)
 		case CharLineStart:
 			o.buf.MoveToLineStart()
 		case CharLineEnd:
@@ -231,7 +385,9 @@ func (o *Operation) ioloop() {
 				o.t.Bell()
 				break
 			}
-			o.buf.Backspace()
+			for i := 0; i < n; i++ {
+				o.buf.Backspace()
+			}
 			if o.IsInCompleteMode() {
 				o.OnComplete()
 			}
@@ -243,13 +399,50 @@ func (o *Operation) ioloop() {
 			ClearScreen(o.w)
 			o.Refresh()
 		case MetaBackspace, CharCtrlW:
-			o.buf.BackEscapeWord()
+			if start, end, ok := o.buf.Region(); ok && r == CharCtrlW {
+				o.buf.KillRange(start, end)
+				break
+			}
+			o.buf.BackEscapeWords(n)
+		case MetaCopy:
+			start, end, ok := o.buf.Region()
+			if !ok {
+				o.t.Bell()
+				break
+			}
+			o.buf.CopyRange(start, end)
+		case CharCtrlSpace:
+			o.buf.SetMark()
+		case CharFindChar, MetaFindCharBackward:
+			if r == MetaFindCharBackward {
+				n = -n
+			}
+			ch := o.readRune()
+			if ch == charCancel {
+				o.abortLine()
+				break
+			}
+			if !o.buf.MoveToNth(ch, n) {
+				o.t.Bell()
+			}
 		case CharCtrlY:
 			o.buf.Yank()
+		case MetaYankLastArg:
+			o.YankLastArg()
+		case MetaYankNthArg:
+			o.YankNthArg(n)
 		case CharEnter, CharCtrlJ:
 			if o.IsSearchMode() {
 				o.ExitSearchMode(false)
 			}
+			if o.GetConfig().HistoryExpansion && !o.expandHistory() {
+				break
+			}
+			if !o.validate() {
+				break
+			}
+			o.buf.SetHint("", "")
+			isUpdateHint = false
 
 			var trailingBuf []rune
 			if 0 < o.buf.Len() && 0 < o.buf.Pos() && o.buf.buf[o.buf.Pos()-1] == '\\' {
@@ -264,8 +457,11 @@ func (o *Operation) ioloop() {
 				if 0 < o.buf.Len() && o.buf.buf[len(o.buf.buf)-1] == '\\' {
 					o.continuousBuf = append(o.continuousBuf, o.buf.Runes()[:o.buf.Len()-1]...)
 					o.continuousBuf = append(o.continuousBuf, ' ')
+					o.continuousText = append(o.continuousText, o.buf.Runes()...)
+					o.continuousText = append(o.continuousText, '\n')
 				} else {
 					o.continuousBuf = nil
+					o.continuousText = nil
 				}
 
 				o.buf.WriteRune('\n')
@@ -283,26 +479,45 @@ func (o *Operation) ioloop() {
 				o.buf.MoveToLineStart()
 			}
 
-			o.outchan <- data
+			// the line is saved before it is returned, because the history
+			// may be switched as soon as Readline returns
 			if !o.GetConfig().DisableAutoSaveHistory {
 				// ignore IO error
-				_ = o.history.New(data)
+				_ = o.getHistory().New(data)
 			} else {
 				isUpdateHistory = false
 			}
+			// the line is sent after the history and the buffer are updated
+			isSubmitted, submitted = true, data
+		case CharShiftTab:
+			o.t.Bell()
 		case CharBackward:
-			o.buf.MoveBackward()
+			for i := 0; i < n; i++ {
+				o.buf.MoveBackward()
+			}
 		case CharForward:
-			o.buf.MoveForward()
+			for i := 0; i < n; i++ {
+				o.buf.MoveForward()
+			}
+		case CharPageUp, CharPageDown:
+			o.historySearchPrefix(r == CharPageDown)
 		case CharPrev:
-			buf := o.history.Prev()
+			if o.GetConfig().HistorySearchPrefix {
+				o.historySearchPrefix(false)
+				break
+			}
+			buf := o.getHistory().Prev()
 			if buf != nil {
 				o.buf.Set(buf)
 			} else {
 				o.t.Bell()
 			}
 		case CharNext:
-			buf, ok := o.history.Next()
+			if o.GetConfig().HistorySearchPrefix {
+				o.historySearchPrefix(true)
+				break
+			}
+			buf, ok := o.getHistory().Next()
 			if ok {
 				o.buf.Set(buf)
 			} else {
@@ -314,6 +529,9 @@ func (o *Operation) ioloop() {
 				if !o.buf.Delete() {
 					o.t.Bell()
 				}
+				for i := 1; i < n; i++ {
+					o.buf.Delete()
+				}
 				break
 			}
 
@@ -323,7 +541,7 @@ func (o *Operation) ioloop() {
 			}
 			o.buf.Reset()
 			isUpdateHistory = false
-			o.history.Revert()
+			o.getHistory().Revert()
 			o.errchan <- io.EOF
 			if o.GetConfig().UniqueEditLine {
 				o.buf.Clean()
@@ -351,7 +569,8 @@ func (o *Operation) ioloop() {
 				remain = remain[:len(remain)-len([]rune(hint))]
 			}
 			isUpdateHistory = false
-			o.history.Revert()
+			isUpdateHint = false
+			o.getHistory().Revert()
 			o.errchan <- &InterruptError{remain}
 		default:
 			if o.IsSearchMode() {
@@ -359,13 +578,28 @@ func (o *Operation) ioloop() {
 				keepInSearchMode = true
 				break
 			}
-			o.buf.WriteRune(r)
+			for i := 0; i < n; i++ {
+				o.buf.WriteRune(r)
+			}
 			if o.IsInCompleteMode() {
 				o.OnComplete()
 				keepInCompleteMode = true
 			}
 		}
 
+		if r != MetaYankLastArg {
+			o.yankArg = nil
+		}
+
+		if o.buf.HasMark() {
+			if regionKeys[r] {
+				o.refreshRegion()
+			} else {
+				o.buf.ClearMark()
+				o.buf.ClearHighlight()
+			}
+		}
+
 		listener := o.GetConfig().Listener
 		if listener != nil {
 			newLine, newPos, ok := listener.OnChange(o.buf.Runes(), o.buf.Pos(), r)
@@ -374,6 +608,10 @@ func (o *Operation) ioloop() {
 			}
 		}
 
+		if inSnippetMode && o.IsSnippetMode() {
+			o.SnippetUpdate()
+		}
+
 		o.m.Lock()
 		if !keepInSearchMode && o.IsSearchMode() {
 			o.ExitSearchMode(false)
@@ -392,9 +630,216 @@ func (o *Operation) ioloop() {
 			o.history.Update(o.buf.Runes(), false)
 		}
 		o.m.Unlock()
+
+		if isUpdateHint {
+			o.updateHint(true)
+		}
+
+		if isSubmitted {
+			o.outchan <- submitted
+		}
+	}
+}
+
+// SetHint shows the hint below the input until it is set to "".
+// It takes precedence over Config.HintFunc.
+func (o *Operation) SetHint(hint string) {
+	o.m.Lock()
+	o.hint = hint
+	o.m.Unlock()
+	if o.t.IsReading() {
+		o.updateHint(true)
+	}
+}
+
+// updateHint shows the validation error, the hint set by SetHint or the
+// hint returned by Config.HintFunc, in that order of precedence.
+func (o *Operation) updateHint(refresh bool) {
+	line, pos := o.buf.Runes(), o.buf.Pos()
+
+	o.m.Lock()
+	if o.invalid != nil && !runes.Equal(o.invalid, line) {
+		o.invalid, o.invalidMsg = nil, ""
+	}
+	hint, style := o.hint, ""
+	if o.invalid != nil {
+		hint, style = o.invalidMsg, validationStyle
+	}
+	o.m.Unlock()
+
+	if hint == "" {
+		if f := o.GetConfig().HintFunc; f != nil {
+			hint = f(line, pos)
+		}
+	}
+
+	if !refresh {
+		o.buf.setHint(hint, style)
+		return
+	}
+	if o.buf.SetHint(hint, style) {
+		if o.IsSearchMode() {
+			o.SearchRefresh(-1)
+		}
+		if o.IsInCompleteMode() {
+			o.CompleteRefresh()
+		}
+	}
+}
+
+// historySearchPrefix replaces the line with the history entry starting with
+// the text before the cursor, and keeps the cursor position.
+func (o *Operation) historySearchPrefix(forward bool) {
+	idx := o.buf.Pos()
+	line := o.buf.Runes()
+	if idx > len(line) {
+		idx = len(line)
+	}
+	buf, ok := o.getHistory().FindPrefix(line[:idx], line, forward)
+	if !ok {
+		o.t.Bell()
+		return
+	}
+	o.buf.SetWithIdx(idx, buf)
+}
+
+// expandHistory expands the history references in the line.
+// It returns false if the line is not to be submitted, because the
+// expansion failed or the expanded line is to be reviewed.
+func (o *Operation) expandHistory() bool {
+	cfg := o.GetConfig()
+	line, changed, err := ExpandHistory(o.buf.Runes(), o.getHistory().Entries(), cfg.FuncSplitArgs)
+	if err != nil {
+		// the reason is shown in the hint area like a validation error
+		o.m.Lock()
+		o.invalid, o.invalidMsg = o.buf.Runes(), err.Error()
+		o.m.Unlock()
+		o.t.Bell()
+		o.t.KickRead()
+		return false
+	}
+	if changed && cfg.HistoryVerify {
+		o.buf.Set(line)
+		o.t.KickRead()
+		return false
+	}
+	if unescaped := UnescapeHistory(line); changed || len(unescaped) != len(line) {
+		o.buf.Set(unescaped)
+	}
+	return true
+}
+
+// reverseKeys maps the keys to the keys working in the opposite direction,
+// which are used for a negative argument.
+var reverseKeys = map[rune]rune{
+	CharForward:   CharBackward,
+	CharBackward:  CharForward,
+	MetaForward:   MetaBackward,
+	MetaBackward:  MetaForward,
+	CharDelete:    CharBackspace,
+	CharBackspace: CharDelete,
+	CharCtrlH:     CharDelete,
+	MetaDelete:    MetaBackspace,
+	MetaBackspace: MetaDelete,
+	CharCtrlW:     MetaDelete,
+}
+
+// readArgument reads the numeric argument started with Meta+digits or
+// Meta+-, and returns the following key and the argument.
+// Once the argument is started, digits without Meta are also read as part
+// of it, and the argument is shown in front of the prompt.
+func (o *Operation) readArgument(r rune) (rune, int) {
+	prompt := string(o.buf.prompt)
+	defer o.buf.UpdatePrompt(prompt)
+	universal := o.GetConfig().UseUniversalArgument
+
+	arg, sign, digits, times := 0, 1, false, 1
+	for {
+		switch {
+		case IsMetaDigit(r):
+			arg = appendArgumentDigit(arg, int(MetaDigit0-r))
+			digits = true
+		case '0' <= r && r <= '9':
+			arg = appendArgumentDigit(arg, int(r-'0'))
+			digits = true
+		case !digits && (r == MetaMinus || r == '-'):
+			sign = -sign
+		case universal && r == CharCtrlU:
+			if digits {
+				// Ctrl+U ends the digits so that the next digit is inserted
+				return o.readRune(), sign * arg
+			}
+			if times*4 <= maxArgument {
+				times *= 4
+			}
+		default:
+			if !digits {
+				arg = times
+			}
+			return r, sign * arg
+		}
+
+		switch {
+		case digits:
+			o.buf.UpdatePrompt(fmt.Sprintf("(arg: %d) ", sign*arg))
+		case 1 < times:
+			o.buf.UpdatePrompt(fmt.Sprintf("(arg: %d) ", sign*times))
+		default:
+			o.buf.UpdatePrompt("(arg: -) ")
+		}
+		r = o.readRune()
+	}
+}
+
+// maxArgument is the upper limit of the numeric argument.
+const maxArgument = 1000000
+
+// appendArgumentDigit appends d to the numeric argument arg. The digit is
+// ignored if the argument would exceed maxArgument.
+func appendArgumentDigit(arg int, d int) int {
+	if maxArgument < arg*10+d {
+		return arg
+	}
+	return arg*10 + d
+}
+
+// regionKeys are the keys that keep the mark active.
+var regionKeys = map[rune]bool{
+	CharCtrlSpace: true,
+	CharForward:   true,
+	CharBackward:  true,
+	MetaForward:   true,
+	MetaBackward:  true,
+	CharLineStart: true,
+	CharLineEnd:   true,
+
+	CharFindChar:         true,
+	MetaFindCharBackward: true,
+}
+
+// refreshRegion highlights the region between the mark and the cursor.
+func (o *Operation) refreshRegion() {
+	if start, end, ok := o.buf.Region(); ok {
+		o.buf.SetHighlight(start, end, "7")
 	}
 }
 
+// HandleCtrlX handles the key sequence starting with Ctrl+X.
+// It returns the key to process instead, or 0.
+func (o *Operation) HandleCtrlX(r rune) rune {
+	switch r {
+	case CharLineEnd:
+		return o.EditExternal()
+	case CharCtrlX:
+		if o.buf.ExchangeMark() {
+			o.refreshRegion()
+			return 0
+		}
+	}
+	o.t.Bell()
+	return 0
+}
+
 func (o *Operation) Stderr() io.Writer {
 	return &wrapWriter{target: o.GetConfig().Stderr, r: o, t: o.t}
 }
@@ -409,6 +854,12 @@ func (o *Operation) String() (string, error) {
 }
 
 func (o *Operation) Runes() ([]rune, error) {
+	return o.RunesContext(context.Background())
+}
+
+// RunesContext reads a line like Runes. If ctx is done before the line is
+// submitted, the line is discarded and ctx.Err() is returned.
+func (o *Operation) RunesContext(ctx context.Context) ([]rune, error) {
 	o.t.EnterRawMode()
 	defer o.t.ExitRawMode()
 
@@ -417,16 +868,88 @@ func (o *Operation) Runes() ([]rune, error) {
 		listener.OnChange(nil, 0, 0)
 	}
 
+	o.updateHint(false)
 	o.buf.Refresh(nil) // print prompt
-	o.t.KickRead()
+	if !o.aborted || !o.t.IsReading() {
+		o.t.KickRead()
+	}
+	o.aborted = false
 	select {
 	case r := <-o.outchan:
 		return r, nil
 	case err := <-o.errchan:
-		if e, ok := err.(*InterruptError); ok {
-			return e.Line, ErrInterrupt
+		return o.readError(err)
+	case <-ctx.Done():
+	}
+
+	o.cancelM.Lock()
+	o.canceled = true
+	o.cancelM.Unlock()
+	select {
+	case o.cancelchan <- struct{}{}:
+	default:
+	}
+
+	select {
+	case r := <-o.outchan:
+		// the line is submitted before ioloop sees the cancellation
+		o.cancelM.Lock()
+		claimed := !o.canceled
+		o.canceled = false
+		o.cancelM.Unlock()
+		if claimed {
+			<-o.errchan
 		}
-		return nil, err
+		return r, nil
+	case err := <-o.errchan:
+		if err == errCanceled {
+			o.aborted = true
+			return nil, ctx.Err()
+		}
+		o.cancelM.Lock()
+		o.canceled = false
+		o.cancelM.Unlock()
+		return o.readError(err)
+	}
+}
+
+func (o *Operation) readError(err error) ([]rune, error) {
+	if e, ok := err.(*InterruptError); ok {
+		return e.Line, ErrInterrupt
+	}
+	return nil, err
+}
+
+// abortLine discards the line being edited if the read is canceled, and
+// erases it from the screen.
+func (o *Operation) abortLine() {
+	o.cancelM.Lock()
+	canceled := o.canceled
+	o.canceled = false
+	o.cancelM.Unlock()
+	if !canceled {
+		return
+	}
+
+	if o.IsSearchMode() {
+		o.ExitSearchMode(true)
+	}
+	if o.IsInCompleteMode() {
+		o.ExitCompleteMode(true)
+	}
+	o.buf.Clean()
+	o.buf.Reset()
+	o.buf.ClearMark()
+	o.continuousBuf = nil
+	o.continuousText = nil
+	o.getHistory().Revert()
+	if o.opVim != nil {
+		o.pending = nil
+	}
+	select {
+	case o.errchan <- errCanceled:
+	case <-o.t.stopChan:
+		// RunesContext may have returned by Close
 	}
 }
 
@@ -470,7 +993,70 @@ func (o *Operation) Close() {
 	case o.errchan <- io.EOF:
 	default:
 	}
-	o.history.Close()
+	o.getHistory().Close()
+	for _, ns := range o.namespaces {
+		ns.history.Close()
+	}
+}
+
+// historyNamespace is the history of a HistoryNamespace.
+type historyNamespace struct {
+	history *opHistory
+	search  *opSearch
+}
+
+func (o *Operation) SetHistoryNamespace(name string) error {
+	o.m.Lock()
+	defer o.m.Unlock()
+	if _, ok := o.cfg.HistoryNamespaces[name]; name != "" && !ok {
+		return fmt.Errorf("history namespace %q is not defined", name)
+	}
+	o.namespace = name
+	o.useHistoryNamespace()
+	return nil
+}
+
+// getHistory returns the history of the current namespace, which is
+// switched by SetHistoryNamespace.
+func (o *Operation) getHistory() *opHistory {
+	o.m.Lock()
+	defer o.m.Unlock()
+	return o.history
+}
+
+// useHistoryNamespace switches the history to the selected namespace.
+// The histories of the namespaces are opened on the first use.
+func (o *Operation) useHistoryNamespace() {
+	def, ok := o.cfg.HistoryNamespaces[o.namespace]
+	if o.namespace == "" || !ok {
+		o.history = o.cfg.opHistory
+		o.opSearch = o.cfg.opSearch
+		return
+	}
+
+	ns, ok := o.namespaces[o.namespace]
+	if !ok {
+		cfg := *o.cfg
+		cfg.HistoryFile = def.File
+		if 0 < def.Limit {
+			cfg.HistoryLimit = def.Limit
+		}
+		h := newOpHistory(&cfg)
+		h.initHistory()
+		if def.Disable {
+			h.Disable()
+		}
+		ns = &historyNamespace{
+			history: h,
+			search:  newOpSearch(o.buf.w, o.buf, h, o.cfg, o.cfg.FuncGetWidth()),
+		}
+		if o.namespaces == nil {
+			o.namespaces = make(map[string]*historyNamespace)
+		}
+		o.namespaces[o.namespace] = ns
+	}
+	o.history = ns.history
+	o.opSearch = ns.search
 }
 
 func (o *Operation) SetHistoryPath(path string) {
@@ -517,11 +1103,72 @@ func (op *Operation) SetConfig(cfg *Config) (*Config, error) {
 	}
 
 	op.opSearch = cfg.opSearch
+	if op.namespace != "" {
+		op.useHistoryNamespace()
+	}
 	return old, nil
 }
 
+// applyOptions applies the one-shot settings, and returns the function to
+// restore the previous settings.
+func (o *Operation) applyOptions(opts ReadlineOptions) (func(), error) {
+	old := o.GetConfig()
+	o.m.Lock()
+	oldCfg, prompt, namespace := o.cfg, o.prompt, o.namespace
+	o.m.Unlock()
+
+	restore := func() {
+		o.SetConfig(oldCfg)
+		o.SetPrompt(prompt)
+		o.SetHistoryNamespace(namespace)
+	}
+
+	cfg := old
+	cfg.Prompt = prompt
+	if opts.Prompt != "" {
+		cfg.Prompt = opts.Prompt
+	}
+	if opts.AutoComplete != nil {
+		cfg.AutoComplete = opts.AutoComplete
+	}
+	if opts.Painter != nil {
+		cfg.Painter = opts.Painter
+	}
+	if opts.MaskRune != 0 {
+		cfg.EnableMask = true
+		cfg.MaskRune = opts.MaskRune
+	}
+	if opts.Validator != nil {
+		cfg.Validator = opts.Validator
+	}
+	if _, err := o.SetConfig(cfg); err != nil {
+		return nil, err
+	}
+	if opts.History != "" {
+		if err := o.SetHistoryNamespace(opts.History); err != nil {
+			restore()
+			return nil, err
+		}
+	}
+
+	if opts.Default != "" {
+		buf := []rune(opts.Default)
+		pos := len(buf)
+		if opts.Pos != nil {
+			switch p := *opts.Pos; {
+			case 0 <= p && p < len(buf):
+				pos = p
+			case p < 0 && -p <= len(buf):
+				pos = len(buf) + p
+			}
+		}
+		o.buf.SetWithIdx(pos, buf)
+	}
+	return restore, nil
+}
+
 func (o *Operation) ResetHistory() {
-	o.history.Reset()
+	o.getHistory().Reset()
 }
 
 func (o *Operation) EnableKillWholeLine() {
@@ -535,7 +1182,7 @@ func (o *Operation) DisableKillWholeLine() {
 // if err is not nil, it just mean it fail to write to file
 // other things goes fine.
 func (o *Operation) SaveHistory(content string) error {
-	return o.history.New([]rune(content))
+	return o.getHistory().New([]rune(content))
 }
 
 func (o *Operation) Refresh() {
@@ -13,6 +13,8 @@ const (
 	VK_CONTROL  = 0x11
 	VK_MENU     = 0x12
 	VK_ESCAPE   = 0x1B
+	VK_PRIOR    = 0x21
+	VK_NEXT     = 0x22
 	VK_LEFT     = 0x25
 	VK_UP       = 0x26
 	VK_RIGHT    = 0x27
@@ -74,13 +76,23 @@ next:
 		case VK_MENU: //alt
 			r.altKey = true
 		case VK_LEFT:
+			if r.ctrlKey {
+				return r.writeSeq(buf, "\033[1;5D")
+			}
 			target = CharBackward
 		case VK_RIGHT:
+			if r.ctrlKey {
+				return r.writeSeq(buf, "\033[1;5C")
+			}
 			target = CharForward
 		case VK_UP:
 			target = CharPrev
 		case VK_DOWN:
 			target = CharNext
+		case VK_PRIOR:
+			return r.writeSeq(buf, "\033[5~")
+		case VK_NEXT:
+			return r.writeSeq(buf, "\033[6~")
 		}
 		if target != 0 {
 			return r.write(buf, target)
@@ -98,6 +110,8 @@ next:
 			char = CharBckSearch
 		case 'S':
 			char = CharFwdSearch
+		case ' ':
+			char = 0
 		}
 	} else if r.altKey {
 		switch char {
@@ -115,6 +129,11 @@ func (r *RawReader) writeEsc(b []byte, char rune) (int, error) {
 	return n + 1, nil
 }
 
+func (r *RawReader) writeSeq(b []byte, seq string) (int, error) {
+	n := copy(b, seq)
+	return n, nil
+}
+
 func (r *RawReader) write(b []byte, char rune) (int, error) {
 	n := copy(b, []byte(string(char)))
 	return n, nil
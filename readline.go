@@ -18,6 +18,7 @@
 package readline
 
 import (
+	"context"
 	"io"
 )
 
@@ -36,8 +37,23 @@ type Config struct {
 	// specify the max length of historys, it's 500 by default, set it to -1 to disable history
 	HistoryLimit           int
 	DisableAutoSaveHistory bool
+	// HistoryNamespaces defines the histories separated from the default
+	// one, which are selected by Instance.SetHistoryNamespace.
+	HistoryNamespaces map[string]HistoryNamespace
 	// enable case-insensitive history searching
 	HistorySearchFold bool
+	// If HistorySearchPrefix is true, Up and Down visit only the history
+	// entries starting with the text before the cursor, like PageUp and
+	// PageDown do.
+	HistorySearchPrefix bool
+	// If HistoryExpansion is true, the history references such as "!!" and
+	// "^old^new" in the line are expanded when the line is submitted.
+	// See ExpandHistory for details. If the expansion fails, the line is kept
+	// and the reason is shown below it.
+	HistoryExpansion bool
+	// If HistoryVerify is true, the expanded line is put back for review
+	// instead of being submitted.
+	HistoryVerify bool
 
 	// AutoCompleter will called once user press TAB
 	AutoComplete AutoCompleter
@@ -51,8 +67,27 @@ type Config struct {
 	// If VimMode is true, readline will in vim.insert mode by default
 	VimMode bool
 
+	// VimModePrefix is displayed in front of the prompt in each vim mode,
+	// e.g. map[int]string{VIM_NORMAL: "[N] ", VIM_INSERT: "[I] "}
+	VimModePrefix map[int]string
+
+	// FuncVimModePrompt rewrites the prompt for the vim mode.
+	// It is used instead of VimModePrefix.
+	FuncVimModePrompt func(mode int, prompt string) string
+
+	// If VimCursorShape is true, the cursor is a block in the vim normal
+	// and visual mode, and a bar in the insert mode
+	VimCursorShape bool
+
+	// Clipboard is used for the "+ register in vim mode.
+	// If nil, "+ is a named register.
+	Clipboard Clipboard
+
 	// Ctrl+U
 	UseKillWholeLine bool
+	// Ctrl+U starts a universal argument, which is 4 or the digits typed
+	// after it, instead of cutting text
+	UseUniversalArgument bool
 
 	InterruptPrompt string
 	EOFPrompt       string
@@ -71,6 +106,43 @@ type Config struct {
 	// it use in IM usually.
 	UniqueEditLine bool
 
+	// WordClassifier defines the words for word operations.
+	// If nil, UnicodeWords is used, and the vim mode uses VimWords.
+	WordClassifier WordClassifier
+
+	// KillWordClassifier defines the words removed by Ctrl+W, such as
+	// PathWords. If nil, WordClassifier is used.
+	KillWordClassifier WordClassifier
+
+	// FuncEditText edits the line with an external editor
+	// on Ctrl+X Ctrl+E, or V in the vim normal mode.
+	// If nil, DefaultEditText is used.
+	FuncEditText func(text []rune) ([]rune, error)
+
+	// If EditorSubmit is true, the line edited with the external editor is
+	// submitted. Otherwise it is left for review.
+	EditorSubmit bool
+
+	// Validator checks the line when Enter is pressed. If it returns an
+	// error, the line is not submitted and the error is shown below the
+	// input until the line is edited. If the error is a *ValidationError,
+	// the cursor moves to its position.
+	// The lines continued by a trailing backslash are not checked alone,
+	// but joined with the line ending them.
+	Validator func(line []rune) error
+
+	// HintFunc returns the hint shown below the input. It is called
+	// whenever the line is changed. The hint set by SetHint takes
+	// precedence.
+	// HintFunc is called synchronously after every key, so it must return
+	// quickly. A slow hint should be computed in another goroutine and
+	// shown with SetHint.
+	HintFunc func(line []rune, pos int) string
+
+	// FuncSplitArgs splits a history entry into the arguments inserted by
+	// Meta+. and Meta+Ctrl+Y. If nil, SplitSQLArgs is used.
+	FuncSplitArgs func(line []rune) [][]rune
+
 	// filter input runes (may be used to disable CtrlZ or for translating some keys to different actions)
 	// -> output = new (translated) rune and true/false if continue with processing this one
 	FuncFilterInputRune func(rune) (rune, bool)
@@ -140,6 +212,12 @@ func (c *Config) Init() error {
 	if c.FuncMakeRaw == nil {
 		c.FuncMakeRaw = rm.Enter
 	}
+	if c.FuncEditText == nil {
+		c.FuncEditText = DefaultEditText
+	}
+	if c.FuncSplitArgs == nil {
+		c.FuncSplitArgs = SplitSQLArgs
+	}
 	if c.FuncExitRaw == nil {
 		c.FuncExitRaw = rm.Exit
 	}
@@ -192,6 +270,11 @@ func (i *Instance) SetPrompt(s string) {
 	i.Operation.SetPrompt(s)
 }
 
+// SetHint shows the hint below the input. An empty hint removes it.
+func (i *Instance) SetHint(s string) {
+	i.Operation.SetHint(s)
+}
+
 func (i *Instance) SetMaskRune(r rune) {
 	i.Operation.SetMaskRune(r)
 }
@@ -273,6 +356,51 @@ func (i *Instance) ReadlineWithDefault(what string) (string, error) {
 	return i.Operation.String()
 }
 
+// ReadlineOptions are the settings for a single Readline call.
+// The zero values keep the current settings.
+type ReadlineOptions struct {
+	Prompt string
+
+	// Default is the initial text of the line.
+	// Pos is the cursor position in Default. If Pos is nil, the cursor is at
+	// the end, and a negative Pos counts back from the end.
+	Default string
+	Pos     *int
+
+	AutoComplete AutoCompleter
+	Painter      Painter
+
+	// If MaskRune is not 0, the input is masked with it.
+	MaskRune rune
+
+	// History is the name of the history namespace.
+	History string
+
+	Validator func(line []rune) error
+}
+
+// ReadlineWithOptions reads a line with the one-shot settings, and restores
+// the previous settings afterwards.
+func (i *Instance) ReadlineWithOptions(ctx context.Context, opts ReadlineOptions) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	restore, err := i.Operation.applyOptions(opts)
+	if err != nil {
+		return "", err
+	}
+	defer restore()
+	return i.ReadlineContext(ctx)
+}
+
+// ReadlineContext reads a line like Readline. If ctx is done before the
+// line is submitted, the line is discarded and ctx.Err() is returned.
+// The Instance can be used for the next read.
+func (i *Instance) ReadlineContext(ctx context.Context) (string, error) {
+	r, err := i.Operation.RunesContext(ctx)
+	return string(r), err
+}
+
 func (i *Instance) SaveHistory(content string) error {
 	return i.Operation.SaveHistory(content)
 }
@@ -338,12 +466,25 @@ func (i *Instance) Refresh() {
 	i.Operation.Refresh()
 }
 
+// SetHistoryNamespace selects the history used by the following Readline
+// calls from Config.HistoryNamespaces. The empty name selects the default
+// history.
+func (i *Instance) SetHistoryNamespace(name string) error {
+	return i.Operation.SetHistoryNamespace(name)
+}
+
+// History returns the history to list and edit the entries.
+// It is the history selected by SetHistoryNamespace.
+func (i *Instance) History() *History {
+	return &History{op: i.Operation}
+}
+
 // HistoryDisable the save of the commands into the history
 func (i *Instance) HistoryDisable() {
-	i.Operation.history.Disable()
+	i.Operation.getHistory().Disable()
 }
 
 // HistoryEnable the save of the commands into the history (default on)
 func (i *Instance) HistoryEnable() {
-	i.Operation.history.Enable()
+	i.Operation.getHistory().Enable()
 }
@@ -1,6 +1,11 @@
 package readline
 
 import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -25,3 +30,390 @@ func TestRace(t *testing.T) {
 
 	rl.Readline()
 }
+
+func TestInstance_ReadlineWithOptions(t *testing.T) {
+	r, w := io.Pipe()
+	rl, err := NewEx(&Config{
+		Prompt:            "> ",
+		Stdin:             r,
+		Stdout:            io.Discard,
+		HistoryNamespaces: map[string]HistoryNamespace{"confirm": {}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rl.Close()
+	defer w.Close()
+
+	pos := 0
+	go w.Write([]byte("x\n"))
+	line, err := rl.ReadlineWithOptions(context.Background(), ReadlineOptions{
+		Prompt:   "? ",
+		Default:  "abc",
+		Pos:      &pos,
+		MaskRune: '*',
+		History:  "confirm",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line != "xabc" {
+		t.Errorf("line = %q, want %q", line, "xabc")
+	}
+	if string(rl.Operation.buf.prompt) != "> " || rl.Operation.GetConfig().EnableMask {
+		t.Errorf("settings are not restored: prompt %q", string(rl.Operation.buf.prompt))
+	}
+
+	pos = -1
+	go w.Write([]byte("y\n"))
+	line, err = rl.ReadlineWithOptions(context.Background(), ReadlineOptions{
+		Default: "ab",
+		Pos:     &pos,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line != "ayb" {
+		t.Errorf("line = %q, want %q", line, "ayb")
+	}
+
+	entries := func() string {
+		var ret []string
+		it := rl.History().Iterator()
+		for it.Next() {
+			ret = append(ret, it.Entry().Line)
+		}
+		return strings.Join(ret, ",")
+	}
+	if result := entries(); result != "ayb" {
+		t.Errorf("default entries = %q, want %q", result, "ayb")
+	}
+	if err := rl.SetHistoryNamespace("confirm"); err != nil {
+		t.Fatal(err)
+	}
+	if result := entries(); result != "xabc" {
+		t.Errorf("confirm entries = %q, want %q", result, "xabc")
+	}
+	if err := rl.SetHistoryNamespace(""); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := rl.ReadlineWithOptions(ctx, ReadlineOptions{}); err != context.Canceled {
+		t.Errorf("error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestInstance_ReadlineContext(t *testing.T) {
+	r, w := io.Pipe()
+	rl, err := NewEx(&Config{
+		Stdin:  r,
+		Stdout: io.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rl.Close()
+
+	go w.Write([]byte("abc"))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := rl.ReadlineContext(ctx); err != context.DeadlineExceeded {
+		t.Errorf("error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	go w.Write([]byte("x\n"))
+	line, err := rl.ReadlineContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line != "x" {
+		t.Errorf("line = %q, want %q", line, "x")
+	}
+}
+
+func TestInstance_ReadlineContextPending(t *testing.T) {
+	r, w := io.Pipe()
+	out := new(syncBuffer)
+	rl, err := NewEx(&Config{
+		Stdin:  r,
+		Stdout: out,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rl.Close()
+	defer w.Close()
+
+	// the reads are canceled while the commands wait for the next key
+	for _, keys := range []string{"a\033" + "2", "a\030", "a\035"} {
+		go w.Write([]byte(keys))
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		if _, err := rl.ReadlineContext(ctx); err != context.DeadlineExceeded {
+			t.Errorf("error = %v, want %v for %q", err, context.DeadlineExceeded, keys)
+		}
+		cancel()
+	}
+	if strings.Contains(out.String(), "\a") {
+		t.Errorf("the canceled commands ring the bell")
+	}
+
+	go w.Write([]byte("x\n"))
+	line, err := rl.ReadlineContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line != "x" {
+		t.Errorf("line = %q, want %q", line, "x")
+	}
+}
+
+func TestInstance_ReadlineValidator(t *testing.T) {
+	r, w := io.Pipe()
+	rl, err := NewEx(&Config{
+		Stdin:  r,
+		Stdout: io.Discard,
+		Validator: func(line []rune) error {
+			pos := -1
+			for i, c := range line {
+				if c == '\'' {
+					if pos < 0 {
+						pos = i
+					} else {
+						pos = -1
+					}
+				}
+			}
+			if 0 <= pos {
+				return &ValidationError{Pos: pos, Err: errors.New("unterminated literal")}
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rl.Close()
+
+	go w.Write([]byte("ab'c\nx'\n"))
+	line, err := rl.Readline()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line != "abx''c" {
+		t.Errorf("line = %q, want %q", line, "abx''c")
+	}
+	if rl.Operation.invalid != nil || len(rl.Operation.buf.hint) != 0 {
+		t.Errorf("validation error is not cleared: %q", string(rl.Operation.buf.hint))
+	}
+}
+
+func TestInstance_ReadlineValidatorContinuation(t *testing.T) {
+	r, w := io.Pipe()
+	rl, err := NewEx(&Config{
+		Stdin:  r,
+		Stdout: io.Discard,
+		Validator: func(line []rune) error {
+			pos := -1
+			for i, c := range line {
+				if c == '\'' {
+					if pos < 0 {
+						pos = i
+					} else {
+						pos = -1
+					}
+				}
+			}
+			if 0 <= pos {
+				return &ValidationError{Pos: pos, Err: errors.New("unterminated literal")}
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rl.Close()
+	defer w.Close()
+
+	go w.Write([]byte("ab'c \\\rd\r'\re'\r'\r"))
+	for _, want := range []string{"ab'c \\", "'d", "e''"} {
+		line, err := rl.Readline()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if line != want {
+			t.Errorf("line = %q, want %q", line, want)
+		}
+	}
+}
+
+func TestInstance_ReadlineVimNormal(t *testing.T) {
+	r, w := io.Pipe()
+	rl, err := NewEx(&Config{
+		Stdin:   r,
+		Stdout:  io.Discard,
+		VimMode: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rl.Close()
+	defer w.Close()
+
+	go w.Write([]byte("abc def\033" + "0dwx\r"))
+	line, err := rl.Readline()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line != "ef" {
+		t.Errorf("line = %q, want %q", line, "ef")
+	}
+}
+
+func TestInstance_ReadlineVimEditor(t *testing.T) {
+	r, w := io.Pipe()
+	rl, err := NewEx(&Config{
+		Stdin:   r,
+		Stdout:  io.Discard,
+		VimMode: true,
+		FuncEditText: func(text []rune) ([]rune, error) {
+			return []rune(strings.ToUpper(string(text))), nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rl.Close()
+	defer w.Close()
+
+	go w.Write([]byte("abc\033" + "v\r"))
+	line, err := rl.Readline()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line != "ABC" {
+		t.Errorf("line = %q, want %q", line, "ABC")
+	}
+}
+
+func TestInstance_ReadlineUniversalArgument(t *testing.T) {
+	tests := []struct {
+		Input  string
+		Expect string
+	}{
+		{"\025x\r", "xxxx"},
+		{"\025\025a\r", strings.Repeat("a", 16)},
+		{"\02512\0250\r", strings.Repeat("0", 12)},
+	}
+	for _, v := range tests {
+		r, w := io.Pipe()
+		rl, err := NewEx(&Config{
+			Stdin:                r,
+			Stdout:               io.Discard,
+			UseUniversalArgument: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		go w.Write([]byte(v.Input))
+		line, err := rl.Readline()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if line != v.Expect {
+			t.Errorf("line = %q, want %q for %q", line, v.Expect, v.Input)
+		}
+		rl.Close()
+		w.Close()
+	}
+}
+
+func TestAppendArgumentDigit(t *testing.T) {
+	tests := []struct {
+		Arg    int
+		Digit  int
+		Expect int
+	}{
+		{0, 3, 3},
+		{12, 3, 123},
+		{100000, 0, 1000000},
+		{999999, 9, 999999},
+		{1000000, 0, 1000000},
+	}
+	for _, v := range tests {
+		if result := appendArgumentDigit(v.Arg, v.Digit); result != v.Expect {
+			t.Errorf("result = %d, want %d for %d, %d", result, v.Expect, v.Arg, v.Digit)
+		}
+	}
+}
+
+type syncBuffer struct {
+	m   sync.Mutex
+	buf strings.Builder
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.m.Lock()
+	defer b.m.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.m.Lock()
+	defer b.m.Unlock()
+	return b.buf.String()
+}
+
+func TestInstance_ReadlineHistoryExpansionError(t *testing.T) {
+	r, w := io.Pipe()
+	out := new(syncBuffer)
+	rl, err := NewEx(&Config{
+		Stdin:            r,
+		Stdout:           out,
+		HistoryExpansion: true,
+		FuncIsTerminal:   func() bool { return true },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rl.Close()
+	defer w.Close()
+
+	go w.Write([]byte("!zz\r\025ok\r"))
+	line, err := rl.Readline()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line != "ok" {
+		t.Errorf("line = %q, want %q", line, "ok")
+	}
+	if !strings.Contains(out.String(), "!zz: event not found") {
+		t.Errorf("output = %q, want the expansion error", out.String())
+	}
+}
+
+func TestInstance_ReadlineHistorySearchPrefix(t *testing.T) {
+	r, w := io.Pipe()
+	rl, err := NewEx(&Config{
+		Stdin:  r,
+		Stdout: io.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rl.Close()
+	defer w.Close()
+
+	rl.SaveHistory("zoo")
+	go w.Write([]byte("z\x14\033[5~\r"))
+	line, err := rl.Readline()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line != "zoo" {
+		t.Errorf("line = %q, want %q", line, "zoo")
+	}
+}
@@ -4,12 +4,14 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"os"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 type MsgType int16
@@ -22,6 +24,9 @@ const (
 	T_RAW
 	T_ERAW // exit raw
 	T_EOF
+	T_EDIT
+	T_EDIT_REPORT
+	T_EDIT_ERROR
 )
 
 type RemoteSvr struct {
@@ -34,6 +39,7 @@ type RemoteSvr struct {
 	isTerminal    bool
 	funcWidthChan func()
 	stopChan      chan struct{}
+	editChan      chan *Message
 
 	dataBufM sync.Mutex
 	dataBuf  bytes.Buffer
@@ -63,6 +69,7 @@ func NewRemoteSvr(conn net.Conn) (*RemoteSvr, error) {
 		writeChan:  make(chan *writeCtx),
 		reciveChan: make(chan struct{}),
 		stopChan:   make(chan struct{}),
+		editChan:   make(chan *Message, 1),
 	}
 	buf := bufio.NewReader(rs.conn)
 
@@ -108,6 +115,7 @@ func (r *RemoteSvr) HandleConfig(cfg *Config) {
 	cfg.FuncMakeRaw = r.EnterRawMode
 	cfg.FuncExitRaw = r.ExitRawMode
 	cfg.FuncGetWidth = r.GetWidth
+	cfg.FuncEditText = r.EditText
 	cfg.FuncOnWidthChanged = func(f func()) {
 		r.funcWidthChan = f
 	}
@@ -171,6 +179,22 @@ func (r *RemoteSvr) ExitRawMode() error {
 	return r.writeMsg(NewMessage(T_ERAW, nil))
 }
 
+// EditText edits text with the editor on the client side.
+func (r *RemoteSvr) EditText(text []rune) ([]rune, error) {
+	if err := r.writeMsg(NewMessage(T_EDIT, []byte(string(text)))); err != nil {
+		return nil, err
+	}
+	select {
+	case m := <-r.editChan:
+		if m.Type == T_EDIT_ERROR {
+			return nil, errors.New(string(m.Data))
+		}
+		return []rune(string(m.Data)), nil
+	case <-r.stopChan:
+		return nil, io.EOF
+	}
+}
+
 func (r *RemoteSvr) writeLoop() {
 	defer r.Close()
 
@@ -219,6 +243,11 @@ func (r *RemoteSvr) readLoop(buf *bufio.Reader) {
 			case r.reciveChan <- struct{}{}:
 			default:
 			}
+		case T_EDIT_REPORT, T_EDIT_ERROR:
+			select {
+			case r.editChan <- m:
+			default:
+			}
 		case T_WIDTH_REPORT:
 			r.GotReportWidth(m.Data)
 		case T_ISTTY_REPORT:
@@ -293,6 +322,12 @@ type RemoteCli struct {
 
 	data  bytes.Buffer
 	dataM sync.Mutex
+
+	source   io.Reader
+	deadline bool
+	editing  bool
+	editM    sync.Mutex
+	editCond *sync.Cond
 }
 
 func NewRemoteCli(conn net.Conn) (*RemoteCli, error) {
@@ -300,6 +335,7 @@ func NewRemoteCli(conn net.Conn) (*RemoteCli, error) {
 		conn:        conn,
 		receiveChan: make(chan struct{}),
 	}
+	r.editCond = sync.NewCond(&r.editM)
 	return r, nil
 }
 
@@ -388,20 +424,83 @@ func (r *RemoteCli) readLoop() {
 			r.raw.Enter()
 		case T_DATA:
 			os.Stdout.Write(msg.Data)
+		case T_EDIT:
+			r.edit(msg.Data)
+		}
+	}
+}
+
+type readDeadliner interface {
+	SetReadDeadline(t time.Time) error
+}
+
+// edit runs the editor requested by the server.
+// The input is not sent to the server while editing.
+func (r *RemoteCli) edit(data []byte) {
+	r.editM.Lock()
+	r.editing = true
+	if d, ok := r.source.(readDeadliner); ok && r.deadline {
+		// interrupt the pending read
+		if err := d.SetReadDeadline(time.Now()); err != nil {
+			// os.Stdin of a terminal does not support deadlines
+			r.deadline = false
+		}
+	}
+	r.editM.Unlock()
+
+	text, err := DefaultEditText([]rune(string(data)))
+
+	r.editM.Lock()
+	r.editing = false
+	r.editM.Unlock()
+	r.editCond.Broadcast()
+
+	if err != nil {
+		r.writeMsg(NewMessage(T_EDIT_ERROR, []byte(err.Error())))
+		return
+	}
+	r.writeMsg(NewMessage(T_EDIT_REPORT, []byte(string(text))))
+}
+
+func (r *RemoteCli) waitEdit() {
+	r.editM.Lock()
+	for r.editing {
+		r.editCond.Wait()
+	}
+	if d, ok := r.source.(readDeadliner); ok && r.deadline {
+		if err := d.SetReadDeadline(time.Time{}); err != nil {
+			r.deadline = false
 		}
 	}
+	r.editM.Unlock()
 }
 
+// ServeBy sends the input read from source to the server.
+// If source supports SetReadDeadline, the read pending while an editor runs
+// is interrupted. Otherwise the first input to the editor may be sent to
+// the server.
 func (r *RemoteCli) ServeBy(source io.Reader) error {
 	if err := r.init(); err != nil {
 		return err
 	}
+	r.source = source
+	_, r.deadline = source.(readDeadliner)
 
 	go func() {
 		defer r.Close()
+		buf := make([]byte, 1024)
 		for {
-			n, _ := io.Copy(r, source)
-			if n == 0 {
+			r.waitEdit()
+			n, err := source.Read(buf)
+			if 0 < n {
+				if _, werr := r.Write(buf[:n]); werr != nil {
+					break
+				}
+			}
+			if err != nil {
+				if errors.Is(err, os.ErrDeadlineExceeded) {
+					continue
+				}
 				break
 			}
 		}
@@ -34,6 +34,16 @@ type RuneBuffer struct {
 
 	lastKill []rune
 
+	hlStart int
+	hlEnd   int
+	hlStyle string
+
+	mark    int
+	hasMark bool
+
+	hint      []rune
+	hintStyle string
+
 	sync.Mutex
 }
 
@@ -172,6 +182,21 @@ func (r *RuneBuffer) WriteRunes(s []rune) {
 	})
 }
 
+// ReplaceRange replaces the runes from start to end with s, and moves the
+// cursor to the end of s.
+func (r *RuneBuffer) ReplaceRange(start, end int, s []rune) {
+	r.Refresh(func() {
+		start, end = r.clampRange(start, end)
+		tail := append(runes.Copy(s), r.buf[end:]...)
+		r.buf = append(r.buf[:start], tail...)
+		r.idx = start + len(s)
+	})
+}
+
+// ReplaceRunes replaces the offset runes before the cursor with s.
+// If s ends with "() " or "() OVER () ", the cursor is placed between the
+// first parentheses. Candidates with an Insertion are inserted by
+// InsertCandidate.
 func (r *RuneBuffer) ReplaceRunes(s []rune, offset int, formatAsIdentifier bool, appendSpace bool) {
 	str := strings.ToUpper(string(s))
 
@@ -215,7 +240,7 @@ func (r *RuneBuffer) ReplaceRunes(s []rune, offset int, formatAsIdentifier bool,
 		}
 	}
 
-	if 0 <= r.idx-1 && !unicode.IsSpace(r.buf[r.idx-1]) && r.buf[r.idx-1] != '(' && !(formatAsIdentifier && r.buf[r.idx-1] == '.') {
+	if r.needsSpaceBefore(r.idx, formatAsIdentifier) {
 		s = append([]rune{' '}, s...)
 	}
 
@@ -228,7 +253,87 @@ func (r *RuneBuffer) ReplaceRunes(s []rune, offset int, formatAsIdentifier bool,
 	}
 }
 
+// needsSpaceBefore reports whether a space is inserted before a candidate
+// inserted at pos to separate it from the previous token.
+func (r *RuneBuffer) needsSpaceBefore(pos int, formatAsIdentifier bool) bool {
+	return 0 < pos && !unicode.IsSpace(r.buf[pos-1]) && r.buf[pos-1] != '(' && !(formatAsIdentifier && r.buf[pos-1] == '.')
+}
+
+// InsertCandidate replaces the offset runes before the cursor with the candidate.
+// If the candidate is a snippet, the tab stops are returned with their
+// positions in the buffer.
+func (r *RuneBuffer) InsertCandidate(c Candidate, offset int) (stops []SnippetStop) {
+	if c.Insertion == nil {
+		r.ReplaceRunes(c.Name, offset, c.FormatAsIdentifier, c.AppendSpace)
+		return
+	}
+
+	ins := *c.Insertion
+	if ins.Snippet {
+		ins.Text, stops = ParseSnippet(ins.Text)
+	}
+	r.Refresh(func() {
+		start, end := r.idx-offset, r.idx
+		if ins.Start != 0 || ins.End != 0 {
+			start, end = r.idx+ins.Start, r.idx+ins.End
+		}
+		if start < 0 {
+			start = 0
+		}
+		if len(r.buf) < end {
+			end = len(r.buf)
+		}
+		if end < start {
+			end = start
+		}
+
+		if 0 < len(ins.Close) && len(ins.Close) <= len(r.buf)-end && runes.EqualFold(r.buf[end:end+len(ins.Close)], ins.Close) {
+			end += len(ins.Close)
+		}
+
+		lead := 0
+		if ins.Start == 0 && ins.End == 0 && 0 < len(ins.Text) && r.needsSpaceBefore(start, c.FormatAsIdentifier) {
+			lead = 1
+		}
+
+		text := make([]rune, 0, lead+len(ins.Text)+len(ins.Close)+1)
+		if 0 < lead {
+			text = append(text, ' ')
+		}
+		text = append(text, ins.Text...)
+		text = append(text, ins.Close...)
+		if c.AppendSpace && !(end < len(r.buf) && r.buf[end] == ' ') {
+			text = append(text, ' ')
+		}
+
+		buf := make([]rune, 0, len(r.buf)-(end-start)+len(text))
+		buf = append(buf, r.buf[:start]...)
+		buf = append(buf, text...)
+		buf = append(buf, r.buf[end:]...)
+		r.buf = buf
+
+		if ins.CursorOffset == 0 {
+			r.idx = start + len(text)
+		} else {
+			r.idx = start + lead + len(ins.Text) + len(ins.Close) - ins.CursorOffset
+		}
+
+		for i := range stops {
+			stops[i].Start += start + lead
+			stops[i].End += start + lead
+		}
+	})
+	return
+}
+
 func (r *RuneBuffer) FormatAsIdentifier(s []rune) ([]rune, int) {
+	return FormatAsIdentifier(s)
+}
+
+// FormatAsIdentifier encloses s in backquotes if s cannot be used as an identifier as it is.
+// The returned offset is the number of runes to move the cursor back to stay in the
+// backquotes when s is a directory.
+func FormatAsIdentifier(s []rune) ([]rune, int) {
 	var endIdx int
 	for i := len(s) - 1; i >= 0; i-- {
 		if unicode.IsSpace(s[i]) {
@@ -318,23 +423,23 @@ func (r *RuneBuffer) Delete() (success bool) {
 }
 
 func (r *RuneBuffer) DeleteWord() {
+	r.DeleteWords(1)
+}
+
+// DeleteWords cuts the next n words at once.
+func (r *RuneBuffer) DeleteWords(n int) {
 	if r.idx == len(r.buf) {
 		return
 	}
-	init := r.idx
-	for init < len(r.buf) && IsWordBreak(r.buf[init]) {
-		init++
-	}
-	for i := init + 1; i < len(r.buf); i++ {
-		if !IsWordBreak(r.buf[i]) && IsWordBreak(r.buf[i-1]) {
-			r.pushKill(r.buf[r.idx : i-1])
-			r.Refresh(func() {
-				r.buf = append(r.buf[:r.idx], r.buf[i-1:]...)
-			})
-			return
+	r.Refresh(func() {
+		classes := r.wordClasses()
+		end := r.idx
+		for i := 0; i < n; i++ {
+			end = forwardWord(classes, end)
 		}
-	}
-	r.Kill()
+		r.pushKill(r.buf[r.idx:end])
+		r.buf = append(r.buf[:r.idx], r.buf[end:]...)
+	})
 }
 
 func (r *RuneBuffer) MoveToPrevWord() (success bool) {
@@ -343,14 +448,7 @@ func (r *RuneBuffer) MoveToPrevWord() (success bool) {
 			return
 		}
 
-		for i := r.idx - 1; i > 0; i-- {
-			if !IsWordBreak(r.buf[i]) && IsWordBreak(r.buf[i-1]) {
-				r.idx = i
-				success = true
-				return
-			}
-		}
-		r.idx = 0
+		r.idx = backwardWord(r.wordClasses(), r.idx)
 		success = true
 	})
 	return
@@ -377,13 +475,65 @@ func (r *RuneBuffer) Kill() {
 	})
 }
 
-func (r *RuneBuffer) Transpose() {
+// killed returns the text saved for Yank.
+func (r *RuneBuffer) killed() []rune {
+	r.Lock()
+	defer r.Unlock()
+	return append([]rune{}, r.lastKill...)
+}
+
+// CopyRange saves the runes from start to end for Yank.
+func (r *RuneBuffer) CopyRange(start, end int) {
+	r.Lock()
+	defer r.Unlock()
+	start, end = r.clampRange(start, end)
+	r.pushKill(r.buf[start:end])
+}
+
+// KillRange cuts the runes from start to end and leaves the cursor at start.
+func (r *RuneBuffer) KillRange(start, end int) {
+	r.Refresh(func() {
+		start, end = r.clampRange(start, end)
+		r.pushKill(r.buf[start:end])
+		r.buf = append(r.buf[:start], r.buf[end:]...)
+		r.idx = start
+	})
+}
+
+// ToggleCaseRange swaps the case of the runes from start to end.
+func (r *RuneBuffer) ToggleCaseRange(start, end int) {
 	r.Refresh(func() {
-		if len(r.buf) == 1 {
-			r.idx++
+		start, end = r.clampRange(start, end)
+		for i := start; i < end; i++ {
+			if unicode.IsUpper(r.buf[i]) {
+				r.buf[i] = unicode.ToLower(r.buf[i])
+			} else {
+				r.buf[i] = unicode.ToUpper(r.buf[i])
+			}
 		}
+	})
+}
+
+func (r *RuneBuffer) clampRange(start, end int) (int, int) {
+	if end < start {
+		start, end = end, start
+	}
+	if start < 0 {
+		start = 0
+	}
+	if len(r.buf) < end {
+		end = len(r.buf)
+	}
+	if end < start {
+		start = end
+	}
+	return start, end
+}
 
+func (r *RuneBuffer) Transpose() {
+	r.Refresh(func() {
 		if len(r.buf) < 2 {
+			r.idx = len(r.buf)
 			return
 		}
 
@@ -397,16 +547,101 @@ func (r *RuneBuffer) Transpose() {
 	})
 }
 
-func (r *RuneBuffer) MoveToNextWord() {
+func (r *RuneBuffer) wordClasses() []int {
+	if r.cfg != nil && r.cfg.WordClassifier != nil {
+		return r.cfg.WordClassifier.Classify(r.buf)
+	}
+	return UnicodeWords.Classify(r.buf)
+}
+
+// TransposeWords swaps the word before the cursor with the word after it,
+// and moves the cursor to the end of them.
+// At the end of the line, the last two words are swapped.
+func (r *RuneBuffer) TransposeWords() (success bool) {
 	r.Refresh(func() {
-		for i := r.idx + 1; i < len(r.buf); i++ {
-			if !IsWordBreak(r.buf[i]) && IsWordBreak(r.buf[i-1]) {
-				r.idx = i
-				return
+		classes := r.wordClasses()
+		end2 := forwardWord(classes, r.idx)
+		start2 := backwardWord(classes, end2)
+		start1 := backwardWord(classes, start2)
+		end1 := forwardWord(classes, start1)
+		if start1 == start2 || start2 < end1 {
+			return
+		}
+
+		buf := make([]rune, 0, len(r.buf))
+		buf = append(buf, r.buf[:start1]...)
+		buf = append(buf, r.buf[start2:end2]...)
+		buf = append(buf, r.buf[end1:start2]...)
+		buf = append(buf, r.buf[start1:end1]...)
+		buf = append(buf, r.buf[end2:]...)
+		r.buf = buf
+		r.idx = end2
+		success = true
+	})
+	return
+}
+
+// UpcaseWord converts the text to the end of the word to upper case.
+func (r *RuneBuffer) UpcaseWord() {
+	r.changeWordCase(func(rs []rune, _ []int) {
+		for i := range rs {
+			rs[i] = unicode.ToUpper(rs[i])
+		}
+	})
+}
+
+// DowncaseWord converts the text to the end of the word to lower case.
+func (r *RuneBuffer) DowncaseWord() {
+	r.changeWordCase(func(rs []rune, _ []int) {
+		for i := range rs {
+			rs[i] = unicode.ToLower(rs[i])
+		}
+	})
+}
+
+// CapitalizeWord capitalizes the text to the end of the word.
+func (r *RuneBuffer) CapitalizeWord() {
+	r.changeWordCase(func(rs []rune, classes []int) {
+		inWord := false
+		for i := range rs {
+			switch {
+			case classes[i] == 0:
+				inWord = false
+			case inWord:
+				rs[i] = unicode.ToLower(rs[i])
+			default:
+				rs[i] = unicode.ToTitle(rs[i])
+				inWord = true
 			}
 		}
+	})
+}
 
-		r.idx = len(r.buf)
+// changeWordCase applies f to the text from the cursor to the end of the
+// word, and moves the cursor to the end of the word.
+func (r *RuneBuffer) changeWordCase(f func(rs []rune, classes []int)) {
+	r.Refresh(func() {
+		classes := r.wordClasses()
+		end := forwardWord(classes, r.idx)
+		f(r.buf[r.idx:end], classes[r.idx:end])
+		r.idx = end
+	})
+}
+
+func (r *RuneBuffer) MoveToNextWord() {
+	r.Refresh(func() {
+		classes := r.wordClasses()
+		i := r.idx
+		if i < len(classes) && classes[i] != 0 {
+			c := classes[i]
+			for i < len(classes) && classes[i] == c {
+				i++
+			}
+		}
+		for i < len(classes) && classes[i] == 0 {
+			i++
+		}
+		r.idx = i
 	})
 }
 
@@ -416,38 +651,56 @@ func (r *RuneBuffer) MoveToEndWord() {
 		if r.idx == len(r.buf) {
 			return
 		}
+
+		classes := r.wordClasses()
+		i := r.idx
 		// if we are at the end of a word already, go to next
-		if !IsWordBreak(r.buf[r.idx]) && IsWordBreak(r.buf[r.idx+1]) {
-			r.idx++
+		if classes[i] != 0 && (i+1 == len(classes) || classes[i+1] != classes[i]) {
+			i++
+		}
+		for i < len(classes) && classes[i] == 0 {
+			i++
+		}
+		if i == len(classes) {
+			r.idx = len(r.buf)
+			return
 		}
 
 		// keep going until at the end of a word
-		for i := r.idx + 1; i < len(r.buf); i++ {
-			if IsWordBreak(r.buf[i]) && !IsWordBreak(r.buf[i-1]) {
-				r.idx = i - 1
-				return
-			}
+		c := classes[i]
+		for i+1 < len(classes) && classes[i+1] == c {
+			i++
 		}
-		r.idx = len(r.buf)
+		r.idx = i
 	})
 }
 
 func (r *RuneBuffer) BackEscapeWord() {
+	r.BackEscapeWords(1)
+}
+
+// BackEscapeWords cuts the previous n words at once.
+// The words are defined by Config.KillWordClassifier.
+func (r *RuneBuffer) BackEscapeWords(n int) {
 	r.Refresh(func() {
 		if r.idx == 0 {
 			return
 		}
-		for i := r.idx - 1; i > 0; i-- {
-			if !IsWordBreak(r.buf[i]) && IsWordBreak(r.buf[i-1]) {
-				r.pushKill(r.buf[i:r.idx])
-				r.buf = append(r.buf[:i], r.buf[r.idx:]...)
-				r.idx = i
-				return
-			}
-		}
 
-		r.buf = r.buf[:0]
-		r.idx = 0
+		classifier := UnicodeWords
+		if r.cfg != nil && r.cfg.KillWordClassifier != nil {
+			classifier = r.cfg.KillWordClassifier
+		} else if r.cfg != nil && r.cfg.WordClassifier != nil {
+			classifier = r.cfg.WordClassifier
+		}
+		classes := classifier.Classify(r.buf)
+		start := r.idx
+		for i := 0; i < n; i++ {
+			start = backwardWord(classes, start)
+		}
+		r.pushKill(r.buf[start:r.idx])
+		r.buf = append(r.buf[:start], r.buf[r.idx:]...)
+		r.idx = start
 	})
 }
 
@@ -486,6 +739,18 @@ func (r *RuneBuffer) MoveToLineEnd() {
 	})
 }
 
+// SetPos moves the cursor to idx.
+func (r *RuneBuffer) SetPos(idx int) {
+	r.Refresh(func() {
+		if idx < 0 {
+			idx = 0
+		} else if len(r.buf) < idx {
+			idx = len(r.buf)
+		}
+		r.idx = idx
+	})
+}
+
 func (r *RuneBuffer) LineCount(width int) int {
 	if width == -1 {
 		width = r.width
@@ -523,6 +788,29 @@ func (r *RuneBuffer) MoveTo(ch rune, prevChar, reverse bool) (success bool) {
 	return
 }
 
+// MoveToNth moves the cursor to the nth occurrence of ch after the cursor,
+// or before the cursor if n is negative.
+// The cursor does not move if there are less than n occurrences.
+func (r *RuneBuffer) MoveToNth(ch rune, n int) (success bool) {
+	r.Refresh(func() {
+		step := 1
+		if n < 0 {
+			step, n = -1, -n
+		}
+		for i := r.idx + step; 0 <= i && i < len(r.buf); i += step {
+			if r.buf[i] != ch {
+				continue
+			}
+			if n--; n == 0 {
+				r.idx = i
+				success = true
+				return
+			}
+		}
+	})
+	return
+}
+
 func (r *RuneBuffer) isInLineEdge() bool {
 	if isWindows {
 		return false
@@ -597,7 +885,11 @@ func (r *RuneBuffer) output() []byte {
 		}
 
 	} else {
-		for _, e := range r.cfg.Painter.Paint(r.buf, r.idx) {
+		painted := r.cfg.Painter.Paint(r.buf, r.idx)
+		if r.hlStyle != "" {
+			painted = paintRegion(painted, r.hlStart, r.hlEnd, r.hlStyle)
+		}
+		for _, e := range painted {
 			if e == '\t' {
 				buf.WriteString(strings.Repeat(" ", TabWidth))
 			} else {
@@ -608,6 +900,9 @@ func (r *RuneBuffer) output() []byte {
 			buf.Write([]byte(" \b"))
 		}
 	}
+	if len(r.hint) > 0 {
+		buf.Write(r.belowOutput("", len(r.buf), len(r.buf)))
+	}
 	// cursor position
 	if len(r.buf) > r.idx {
 		buf.Write(r.getBackspaceSequence())
@@ -615,6 +910,87 @@ func (r *RuneBuffer) output() []byte {
 	return buf.Bytes()
 }
 
+// belowOutput prints text and the hint on the lines below the input.
+// The cursor moves from the position from in the input to the position to.
+func (r *RuneBuffer) belowOutput(text string, from, to int) []byte {
+	var lines []string
+	if text != "" {
+		lines = strings.Split(text, "\n")
+	}
+	if len(r.hint) > 0 {
+		hint := string(r.hint)
+		if r.hintStyle != "" {
+			hint = "\033[" + r.hintStyle + "m" + hint + "\033[0m"
+		}
+		lines = append(lines, strings.Split(hint, "\n")...)
+	}
+	if len(lines) == 0 || r.width == 0 {
+		return nil
+	}
+
+	rows := 0
+	for _, line := range lines {
+		rows += r.rowCount([]rune(line))
+	}
+	_, fromRow := r.cursorAt(from)
+	x, toRow := r.cursorAt(to)
+	lastRow := len(r.getSplitByLine(r.buf)) - 1
+
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString("\r" + strings.Repeat("\n", lastRow-fromRow+1))
+	buf.WriteString("\033[J")
+	buf.WriteString(strings.Join(lines, "\r\n"))
+	buf.WriteString("\r\033[" + strconv.Itoa(lastRow-toRow+rows) + "A")
+	if x > 0 {
+		buf.WriteString("\033[" + strconv.Itoa(x) + "C")
+	}
+	return buf.Bytes()
+}
+
+// PrintBelow prints text and the hint below the input. The cursor moves
+// from the position from in the input back to the cursor of the buffer.
+func (r *RuneBuffer) PrintBelow(text string, from int) {
+	r.Lock()
+	r.w.Write(r.belowOutput(text, from, r.idx))
+	r.Unlock()
+}
+
+// wrapAt moves the cursor onto the next row if pos starts a wrapped row.
+// After the rune before pos is written, the terminal keeps the cursor at
+// the end of the row, while cursorAt places it on the next row.
+func (r *RuneBuffer) wrapAt(pos int) {
+	if r.width == 0 {
+		return
+	}
+	if x, row := r.cursorAt(pos); x != 0 || row == 0 {
+		return
+	}
+	next := ' '
+	if pos < len(r.buf) && r.buf[pos] != '\t' {
+		next = r.buf[pos]
+	}
+	r.w.Write([]byte(string(next) + strings.Repeat("\b", runes.Width(next))))
+}
+
+// cursorAt returns the column and the row of the position in the input.
+func (r *RuneBuffer) cursorAt(pos int) (x, row int) {
+	sp := r.getSplitByLine(r.buf[:pos])
+	x = runes.WidthAll([]rune(sp[len(sp)-1]))
+	if len(sp) == 1 {
+		x += r.promptLen()
+	}
+	return x % r.width, len(sp) - 1
+}
+
+// rowCount returns the number of the screen rows of the line.
+func (r *RuneBuffer) rowCount(line []rune) int {
+	n := LineCount(r.width, runes.WidthAll(runes.ColorFilter(line)))
+	if n == 0 {
+		return 1
+	}
+	return n
+}
+
 func (r *RuneBuffer) getBackspaceSequence() []byte {
 	var sep = map[int]bool{}
 
@@ -649,6 +1025,11 @@ func (r *RuneBuffer) Reset() []rune {
 	ret := runes.Copy(r.buf)
 	r.buf = r.buf[:0]
 	r.idx = 0
+	if r.hasMark {
+		r.hasMark = false
+		r.hlStart, r.hlEnd, r.hlStyle = 0, 0, ""
+	}
+	r.hint, r.hintStyle = nil, ""
 	return ret
 }
 
@@ -677,6 +1058,120 @@ func (r *RuneBuffer) SetStyle(start, end int, style string) {
 	// TODO: move back
 }
 
+// SetMark sets the mark at the cursor. The region is the runes between
+// the mark and the cursor.
+func (r *RuneBuffer) SetMark() {
+	r.Lock()
+	r.mark, r.hasMark = r.idx, true
+	r.Unlock()
+}
+
+func (r *RuneBuffer) ClearMark() {
+	r.Lock()
+	r.hasMark = false
+	r.Unlock()
+}
+
+func (r *RuneBuffer) HasMark() bool {
+	r.Lock()
+	defer r.Unlock()
+	return r.hasMark
+}
+
+// Region returns the range between the mark and the cursor.
+// It returns false if the mark is not set.
+func (r *RuneBuffer) Region() (start, end int, ok bool) {
+	r.Lock()
+	defer r.Unlock()
+	if !r.hasMark {
+		return r.idx, r.idx, false
+	}
+	start, end = r.clampRange(r.mark, r.idx)
+	return start, end, true
+}
+
+// ExchangeMark swaps the cursor and the mark.
+func (r *RuneBuffer) ExchangeMark() (success bool) {
+	r.Refresh(func() {
+		if !r.hasMark {
+			return
+		}
+		if len(r.buf) < r.mark {
+			r.mark = len(r.buf)
+		}
+		r.idx, r.mark = r.mark, r.idx
+		success = true
+	})
+	return
+}
+
+// SetHighlight shows the runes from start to end with the SGR style.
+func (r *RuneBuffer) SetHighlight(start, end int, style string) {
+	r.Refresh(func() {
+		r.hlStart, r.hlEnd, r.hlStyle = start, end, style
+	})
+}
+
+func (r *RuneBuffer) ClearHighlight() {
+	r.Refresh(func() {
+		r.hlStart, r.hlEnd, r.hlStyle = 0, 0, ""
+	})
+}
+
+// SetHint shows the hint with the SGR style on the lines below the input.
+// An empty hint removes it. It returns false if the hint is not changed.
+func (r *RuneBuffer) SetHint(hint, style string) bool {
+	r.Lock()
+	changed := string(r.hint) != hint || r.hintStyle != style
+	r.Unlock()
+	if changed {
+		r.Refresh(func() {
+			r.hint, r.hintStyle = []rune(hint), style
+		})
+	}
+	return changed
+}
+
+// setHint sets the hint without redrawing the line.
+func (r *RuneBuffer) setHint(hint, style string) {
+	r.Lock()
+	r.hint, r.hintStyle = []rune(hint), style
+	r.Unlock()
+}
+
+// paintRegion wraps the runes from start to end of the painted line with
+// the SGR style. Escape sequences in painted are not counted as runes.
+func paintRegion(painted []rune, start, end int, style string) []rune {
+	if end <= start {
+		return painted
+	}
+
+	ret := make([]rune, 0, len(painted)+len(style)+8)
+	pos := 0
+	for i := 0; i < len(painted); i++ {
+		if painted[i] == '\033' && i+1 < len(painted) && painted[i+1] == '[' {
+			j := runes.Index('m', painted[i+2:])
+			if -1 < j {
+				ret = append(ret, painted[i:i+j+3]...)
+				i += j + 2
+				continue
+			}
+		}
+		if pos == start {
+			ret = append(ret, []rune("\033["+style+"m")...)
+		}
+		ret = append(ret, painted[i])
+		pos++
+		if pos == end {
+			ret = append(ret, []rune("\033[0m")...)
+		}
+	}
+	if start < pos && pos < end {
+		ret = append(ret, []rune("\033[0m")...)
+	}
+	return ret
+}
+
 func (r *RuneBuffer) SetWithIdx(idx int, buf []rune) {
 	r.Refresh(func() {
 		r.buf = buf
@@ -694,6 +1189,13 @@ func (r *RuneBuffer) SetPrompt(prompt string) {
 	r.Unlock()
 }
 
+// UpdatePrompt replaces the prompt and redraws the line.
+func (r *RuneBuffer) UpdatePrompt(prompt string) {
+	r.Refresh(func() {
+		r.prompt = []rune(prompt)
+	})
+}
+
 func (r *RuneBuffer) cleanOutput(w io.Writer, idxLine int) {
 	buf := bufio.NewWriter(w)
 
@@ -1,6 +1,7 @@
 package readline
 
 import (
+	"bytes"
 	"testing"
 )
 
@@ -229,3 +230,382 @@ func TestRuneBuffer_FormatAsIdentifier(t *testing.T) {
 		}
 	}
 }
+
+func withoutSpace(c Candidate) Candidate {
+	c.AppendSpace = false
+	return c
+}
+
+var runeBufferInsertCandidateTests = []struct {
+	Candidate Candidate
+	Offset    int
+	Buf       string
+	Idx       int
+	Expect    string
+	ExpectIdx int
+}{
+	{
+		Candidate: FunctionCandidate("COUNT"),
+		Offset:    2,
+		Buf:       "SELECT co",
+		Idx:       9,
+		Expect:    "SELECT COUNT() ",
+		ExpectIdx: 13,
+	},
+	{
+		Candidate: FunctionCandidate("COUNT"),
+		Offset:    2,
+		Buf:       "SELECT co) FROM t",
+		Idx:       9,
+		Expect:    "SELECT COUNT() FROM t",
+		ExpectIdx: 13,
+	},
+	{
+		Candidate: AnalyticFunctionCandidate("RANK"),
+		Offset:    3,
+		Buf:       "SELECT foo) over ()",
+		Idx:       10,
+		Expect:    "SELECT RANK() OVER () ",
+		ExpectIdx: 12,
+	},
+	{
+		Candidate: withoutSpace(FunctionCandidate("bar")),
+		Offset:    0,
+		Buf:       "abcdef ",
+		Idx:       7,
+		Expect:    "abcdef bar()",
+		ExpectIdx: 11,
+	},
+	{
+		Candidate: withoutSpace(FunctionCandidate("bar")),
+		Offset:    4,
+		Buf:       "abcdef foo()",
+		Idx:       11,
+		Expect:    "abcdef bar()",
+		ExpectIdx: 11,
+	},
+	{
+		Candidate: withoutSpace(AnalyticFunctionCandidate("bar")),
+		Offset:    0,
+		Buf:       "abcdef ",
+		Idx:       7,
+		Expect:    "abcdef bar() OVER ()",
+		ExpectIdx: 11,
+	},
+	{
+		Candidate: withoutSpace(AnalyticFunctionCandidate("bar")),
+		Offset:    4,
+		Buf:       "abcdef foo() over ()",
+		Idx:       11,
+		Expect:    "abcdef bar() OVER ()",
+		ExpectIdx: 11,
+	},
+	{
+		Candidate: FunctionCandidate("COUNT"),
+		Offset:    0,
+		Buf:       "SELECT",
+		Idx:       6,
+		Expect:    "SELECT COUNT() ",
+		ExpectIdx: 13,
+	},
+	{
+		Candidate: FunctionCandidate("COUNT"),
+		Offset:    0,
+		Buf:       "SELECT (",
+		Idx:       8,
+		Expect:    "SELECT (COUNT() ",
+		ExpectIdx: 14,
+	},
+	{
+		Candidate: IdentifierCandidate("", true),
+		Offset:    0,
+		Buf:       "SELECT",
+		Idx:       6,
+		Expect:    "SELECT ",
+		ExpectIdx: 7,
+	},
+	{
+		Candidate: IdentifierCandidate("column", false),
+		Offset:    0,
+		Buf:       "SELECT t.",
+		Idx:       9,
+		Expect:    "SELECT t.column",
+		ExpectIdx: 15,
+	},
+	{
+		Candidate: IdentifierCandidate("/path/to/dir/", true),
+		Offset:    6,
+		Buf:       "FROM `/path`",
+		Idx:       11,
+		Expect:    "FROM `/path/to/dir/` ",
+		ExpectIdx: 19,
+	},
+	{
+		Candidate: Candidate{
+			Name:      []rune("value "),
+			Insertion: &Insertion{Text: []rune("value"), Start: -2, End: 3},
+		},
+		Buf:       "abc def ghi",
+		Idx:       5,
+		Expect:    "abcvalueghi",
+		ExpectIdx: 8,
+	},
+	{
+		Candidate: Candidate{Name: []rune("replace "), AppendSpace: true},
+		Offset:    3,
+		Buf:       "abcdef ghi",
+		Idx:       10,
+		Expect:    "abcdef replace ",
+		ExpectIdx: 15,
+	},
+}
+
+func TestRuneBuffer_InsertCandidate(t *testing.T) {
+	buf := new(RuneBuffer)
+	for _, v := range runeBufferInsertCandidateTests {
+		buf.Erase()
+		buf.WriteString(v.Buf)
+		buf.idx = v.Idx
+		buf.InsertCandidate(v.Candidate, v.Offset)
+		result := string(buf.Runes())
+		if result != v.Expect || buf.idx != v.ExpectIdx {
+			t.Errorf("result = %q, want %q for %q", result, v.Expect, v.Candidate.StringName())
+			t.Errorf("index = %d, want %d for %q", buf.idx, v.ExpectIdx, v.Candidate.StringName())
+		}
+	}
+}
+
+var paintRegionTests = []struct {
+	Input  string
+	Start  int
+	End    int
+	Expect string
+}{
+	{
+		Input:  "abcdef",
+		Start:  1,
+		End:    3,
+		Expect: "a\033[7mbc\033[0mdef",
+	},
+	{
+		Input:  "\033[1mab\033[0mcdef",
+		Start:  1,
+		End:    3,
+		Expect: "\033[1ma\033[7mb\033[0mc\033[0mdef",
+	},
+	{
+		Input:  "abc",
+		Start:  1,
+		End:    5,
+		Expect: "a\033[7mbc\033[0m",
+	},
+	{
+		Input:  "abc",
+		Start:  1,
+		End:    1,
+		Expect: "abc",
+	},
+}
+
+func TestPaintRegion(t *testing.T) {
+	for _, v := range paintRegionTests {
+		result := string(paintRegion([]rune(v.Input), v.Start, v.End, "7"))
+		if result != v.Expect {
+			t.Errorf("result = %q, want %q for %q", result, v.Expect, v.Input)
+		}
+	}
+}
+
+var runeBufferWordTests = []struct {
+	Op        string
+	Buf       string
+	Idx       int
+	Expect    string
+	ExpectIdx int
+}{
+	{Op: "transpose", Buf: "select col tbl", Idx: 10, Expect: "select tbl col", ExpectIdx: 14},
+	{Op: "transpose", Buf: "select col tbl", Idx: 14, Expect: "select tbl col", ExpectIdx: 14},
+	{Op: "transpose", Buf: "名前, 年齢", Idx: 2, Expect: "年齢, 名前", ExpectIdx: 6},
+	{Op: "transpose", Buf: "col", Idx: 1, Expect: "col", ExpectIdx: 1},
+	{Op: "upcase", Buf: "select café from t", Idx: 6, Expect: "select CAFÉ from t", ExpectIdx: 11},
+	{Op: "downcase", Buf: "SELECT Naïve", Idx: 2, Expect: "SElect Naïve", ExpectIdx: 6},
+	{Op: "capitalize", Buf: "select éTÉ from t", Idx: 6, Expect: "select Été from t", ExpectIdx: 10},
+	{Op: "capitalize", Buf: "データ col", Idx: 0, Expect: "データ col", ExpectIdx: 3},
+	{Op: "next", Buf: "select a.café, b", Idx: 7, Expect: "select a.café, b", ExpectIdx: 9},
+	{Op: "next", Buf: "select col", Idx: 7, Expect: "select col", ExpectIdx: 10},
+	{Op: "prev", Buf: "select a.café, b", Idx: 14, Expect: "select a.café, b", ExpectIdx: 9},
+	{Op: "end", Buf: "select a.café, b", Idx: 5, Expect: "select a.café, b", ExpectIdx: 7},
+	{Op: "end", Buf: "select col", Idx: 9, Expect: "select col", ExpectIdx: 10},
+	{Op: "delete", Buf: "select a.café, b", Idx: 8, Expect: "select a, b", ExpectIdx: 8},
+	{Op: "backkill", Buf: "select a.café", Idx: 13, Expect: "select a.", ExpectIdx: 9},
+	{Op: "backkill", Buf: "  col", Idx: 2, Expect: "col", ExpectIdx: 0},
+	{Op: "delete3", Buf: "select a, b from t", Idx: 6, Expect: "select t", ExpectIdx: 6},
+	{Op: "backkill2", Buf: "select a, b from t", Idx: 11, Expect: "select  from t", ExpectIdx: 7},
+}
+
+func TestRuneBuffer_WordOperations(t *testing.T) {
+	buf := new(RuneBuffer)
+	for _, v := range runeBufferWordTests {
+		buf.Set([]rune(v.Buf))
+		buf.idx = v.Idx
+		switch v.Op {
+		case "transpose":
+			buf.TransposeWords()
+		case "upcase":
+			buf.UpcaseWord()
+		case "downcase":
+			buf.DowncaseWord()
+		case "capitalize":
+			buf.CapitalizeWord()
+		case "next":
+			buf.MoveToNextWord()
+		case "prev":
+			buf.MoveToPrevWord()
+		case "end":
+			buf.MoveToEndWord()
+		case "delete":
+			buf.DeleteWord()
+		case "backkill":
+			buf.BackEscapeWord()
+		case "delete3":
+			buf.DeleteWords(3)
+		case "backkill2":
+			buf.BackEscapeWords(2)
+		}
+		result := string(buf.Runes())
+		if result != v.Expect || buf.idx != v.ExpectIdx {
+			t.Errorf("result = %q (%d), want %q (%d) for %s %q", result, buf.idx, v.Expect, v.ExpectIdx, v.Op, v.Buf)
+		}
+	}
+}
+
+func TestRuneBuffer_Mark(t *testing.T) {
+	buf := new(RuneBuffer)
+	buf.Set([]rune("select id from t"))
+	if _, _, ok := buf.Region(); ok {
+		t.Errorf("region is active without the mark")
+	}
+	if buf.ExchangeMark() {
+		t.Errorf("mark is exchanged without the mark")
+	}
+
+	buf.idx = 10
+	buf.SetMark()
+	buf.MoveToLineStart()
+	buf.MoveToNextWord()
+	if start, end, _ := buf.Region(); start != 7 || end != 10 {
+		t.Errorf("region = (%d, %d), want (%d, %d)", start, end, 7, 10)
+	}
+
+	buf.ExchangeMark()
+	if buf.idx != 10 || buf.mark != 7 {
+		t.Errorf("cursor and mark = (%d, %d), want (%d, %d)", buf.idx, buf.mark, 10, 7)
+	}
+
+	start, end, _ := buf.Region()
+	buf.KillRange(start, end)
+	if string(buf.Runes()) != "select from t" || string(buf.lastKill) != "id " {
+		t.Errorf("result = %q, kill = %q", string(buf.Runes()), string(buf.lastKill))
+	}
+
+	buf.Reset()
+	if buf.HasMark() {
+		t.Errorf("mark is not cleared by Reset")
+	}
+}
+
+var runeBufferMoveToNthTests = []struct {
+	Idx       int
+	Ch        rune
+	N         int
+	ExpectIdx int
+	Success   bool
+}{
+	{Idx: 0, Ch: ',', N: 1, ExpectIdx: 1, Success: true},
+	{Idx: 0, Ch: ',', N: 3, ExpectIdx: 5, Success: true},
+	{Idx: 1, Ch: ',', N: 1, ExpectIdx: 3, Success: true},
+	{Idx: 0, Ch: ',', N: 4, ExpectIdx: 0, Success: false},
+	{Idx: 6, Ch: ',', N: -2, ExpectIdx: 3, Success: true},
+	{Idx: 6, Ch: 'x', N: -1, ExpectIdx: 6, Success: false},
+}
+
+func TestRuneBuffer_MoveToNth(t *testing.T) {
+	buf := new(RuneBuffer)
+	for _, v := range runeBufferMoveToNthTests {
+		buf.Set([]rune("a,b,c,d"))
+		buf.idx = v.Idx
+		success := buf.MoveToNth(v.Ch, v.N)
+		if success != v.Success || buf.idx != v.ExpectIdx {
+			t.Errorf("result = %t (%d), want %t (%d) for %q %d", success, buf.idx, v.Success, v.ExpectIdx, v.Ch, v.N)
+		}
+	}
+}
+
+var runeBufferBelowOutputTests = []struct {
+	Buf    string
+	Idx    int
+	From   int
+	Text   string
+	Hint   string
+	Style  string
+	Expect string
+}{
+	{Buf: "abc", Idx: 1, From: 1, Hint: "hint", Expect: "\r\n\033[Jhint\r\033[1A\033[3C"},
+	{Buf: "abc", Idx: 3, From: 3, Hint: "0123456789ab", Expect: "\r\n\033[J0123456789ab\r\033[2A\033[5C"},
+	{Buf: "abc", Idx: 3, From: 3, Hint: "0123456789ab", Style: "31", Expect: "\r\n\033[J\033[31m0123456789ab\033[0m\r\033[2A\033[5C"},
+	{Buf: "abcdefghij", Idx: 0, From: 0, Hint: "hint", Expect: "\r\n\n\033[Jhint\r\033[2A\033[2C"},
+	{Buf: "abc", Idx: 3, From: 3, Text: "menu", Hint: "hint", Expect: "\r\n\033[Jmenu\r\nhint\r\033[2A\033[5C"},
+	{Buf: "abc", Idx: 3, From: 3, Expect: ""},
+	// a wrapped line with the completions and the hint
+	{Buf: "abcdefghijkl", Idx: 12, From: 12, Text: "ab cd ef \ngh ", Hint: "hint", Expect: "\r\n\033[Jab cd ef \r\ngh \r\nhint\r\033[3A\033[4C"},
+	{Buf: "abcdefghijkl", Idx: 2, From: 2, Text: "ab cd ef \ngh ", Hint: "hint", Expect: "\r\n\n\033[Jab cd ef \r\ngh \r\nhint\r\033[4A\033[4C"},
+	// the cursor moves back from the end of the search match
+	{Buf: "select abc", Idx: 7, From: 10, Text: "bck-i-search: abc", Hint: "hint", Expect: "\r\n\033[Jbck-i-search: abc\r\nhint\r\033[4A\033[9C"},
+}
+
+func TestRuneBuffer_BelowOutput(t *testing.T) {
+	buf := &RuneBuffer{prompt: []rune("> "), width: 10}
+	for _, v := range runeBufferBelowOutputTests {
+		buf.buf = []rune(v.Buf)
+		buf.idx = v.Idx
+		buf.hint = []rune(v.Hint)
+		buf.hintStyle = v.Style
+		result := string(buf.belowOutput(v.Text, v.From, v.Idx))
+		if result != v.Expect {
+			t.Errorf("result = %q, want %q for %q", result, v.Expect, v.Hint)
+		}
+	}
+}
+
+func TestRuneBuffer_PrintBelow(t *testing.T) {
+	out := &bytes.Buffer{}
+	cfg := &Config{FuncIsTerminal: func() bool { return false }}
+	op := &Operation{buf: NewRuneBuffer(out, "> ", cfg, 10)}
+	op.buf.WriteString("abcdefghijkl")
+	op.buf.setHint("hint", "")
+
+	// completions below a wrapped line
+	o := newOpCompleter(out, op, 10)
+	o.inCompleteMode = true
+	for _, name := range []string{"ab", "cd", "ef", "gh"} {
+		o.candidates = append(o.candidates, Candidate{Name: []rune(name)})
+	}
+	o.CompleteRefresh()
+	expect := "\r\n\033[Jab cd ef \r\ngh \r\nhint\r\033[3A\033[4C"
+	if out.String() != expect {
+		t.Errorf("completions = %q, want %q", out.String(), expect)
+	}
+
+	// the search match ending at the end of the first row
+	op.buf.SetWithIdx(7, []rune("select abcd"))
+	op.buf.setHint("hint", "")
+	out.Reset()
+	s := newOpSearch(out, op.buf, nil, cfg, 10)
+	s.dir, s.data, s.markStart, s.markEnd = S_DIR_BCK, []rune("a"), 7, 8
+	s.SearchRefresh(-1)
+	expect = "\033[4ma\033[0mb\b" +
+		"\r\n\033[Jbck-i-search: a\033[4m \033[0m\r\nhint\r\033[4A\033[9C"
+	if out.String() != expect {
+		t.Errorf("search = %q, want %q", out.String(), expect)
+	}
+}
@@ -3,7 +3,6 @@ package readline
 import (
 	"bytes"
 	"container/list"
-	"fmt"
 	"io"
 )
 
@@ -75,9 +74,7 @@ func (o *opSearch) search(isChange bool) bool {
 		o.SearchRefresh(-2)
 		return false
 	}
-	o.history.current = elem
-
-	item := o.history.showItem(o.history.current.Value)
+	item := o.history.MoveTo(elem)
 	start, end := 0, 0
 	if o.dir == S_DIR_BCK {
 		start, end = idx, idx+len(o.data)
@@ -103,7 +100,7 @@ func (o *opSearch) SearchMode(dir int) bool {
 	alreadyInMode := o.inMode
 	o.inMode = true
 	o.dir = dir
-	o.source = o.history.current
+	o.source = o.history.Current()
 	if alreadyInMode {
 		o.search(false)
 	} else {
@@ -114,8 +111,7 @@ func (o *opSearch) SearchMode(dir int) bool {
 
 func (o *opSearch) ExitSearchMode(revert bool) {
 	if revert {
-		o.history.current = o.source
-		o.buf.Set(o.history.showItem(o.history.current.Value))
+		o.buf.Set(o.history.MoveTo(o.source))
 	}
 	o.markStart, o.markEnd = 0, 0
 	o.state = S_STATE_FOUND
@@ -130,21 +126,14 @@ func (o *opSearch) SearchRefresh(x int) {
 	} else if x >= 0 {
 		o.state = S_STATE_FOUND
 	}
-	if x < 0 {
-		x = o.buf.idx
-	}
-	x = o.buf.CurrentWidth(x)
-	x += o.buf.PromptLen()
-	x = x % o.width
-
+	from := o.buf.Pos()
 	if o.markStart > 0 {
 		o.buf.SetStyle(o.markStart, o.markEnd, "4")
+		from = o.markEnd
+		o.buf.wrapAt(from)
 	}
 
-	lineCnt := o.buf.CursorLineCount()
 	buf := bytes.NewBuffer(nil)
-	buf.Write(bytes.Repeat([]byte("\n"), lineCnt))
-	buf.WriteString("\033[J")
 	if o.state == S_STATE_FAILING {
 		buf.WriteString("failing ")
 	}
@@ -154,11 +143,7 @@ func (o *opSearch) SearchRefresh(x int) {
 		buf.WriteString("fwd")
 	}
 	buf.WriteString("-i-search: ")
-	buf.WriteString(string(o.data))         // keyword
-	buf.WriteString("\033[4m \033[0m")      // _
-	fmt.Fprintf(buf, "\r\033[%dA", lineCnt) // move prev
-	if x > 0 {
-		fmt.Fprintf(buf, "\033[%dC", x) // move forward
-	}
-	o.w.Write(buf.Bytes())
+	buf.WriteString(string(o.data))    // keyword
+	buf.WriteString("\033[4m \033[0m") // _
+	o.buf.PrintBelow(buf.String(), from)
 }
@@ -0,0 +1,259 @@
+package readline
+
+import (
+	"sort"
+	"unicode"
+)
+
+// SnippetStop is a tab stop in an expanded snippet.
+// Start and End are the offsets of the placeholder text.
+type SnippetStop struct {
+	Index int
+	Start int
+	End   int
+}
+
+// ParseSnippet expands a snippet template and returns the tab stops.
+//
+// Tab stops are written as "$1" or "${1}", and placeholders with default
+// text as "${1:text}". "$0" is the final position of the cursor.
+// "\$" is a literal dollar sign.
+// Stops are ordered by index, and the final position comes last.
+func ParseSnippet(template []rune) ([]rune, []SnippetStop) {
+	text := make([]rune, 0, len(template))
+	var stops []SnippetStop
+	seen := make(map[int]bool)
+
+	readIndex := func(i int) (int, int) {
+		n := 0
+		j := i
+		for j < len(template) && unicode.IsDigit(template[j]) {
+			n = n*10 + int(template[j]-'0')
+			j++
+		}
+		if j == i {
+			return -1, i
+		}
+		return n, j
+	}
+
+	addStop := func(index int, start int) {
+		if seen[index] {
+			return
+		}
+		seen[index] = true
+		stops = append(stops, SnippetStop{Index: index, Start: start, End: len(text)})
+	}
+
+	for i := 0; i < len(template); i++ {
+		r := template[i]
+		if r == '\\' && i+1 < len(template) && (template[i+1] == '$' || template[i+1] == '}' || template[i+1] == '\\') {
+			i++
+			text = append(text, template[i])
+			continue
+		}
+		if r != '$' || i+1 == len(template) {
+			text = append(text, r)
+			continue
+		}
+
+		if template[i+1] != '{' {
+			if index, next := readIndex(i + 1); -1 < index {
+				addStop(index, len(text))
+				i = next - 1
+				continue
+			}
+			text = append(text, r)
+			continue
+		}
+
+		index, next := readIndex(i + 2)
+		if index < 0 || next == len(template) || (template[next] != ':' && template[next] != '}') {
+			text = append(text, r)
+			continue
+		}
+
+		start := len(text)
+		i = next
+		if template[i] == ':' {
+			for i++; i < len(template) && template[i] != '}'; i++ {
+				if template[i] == '\\' && i+1 < len(template) {
+					i++
+				}
+				text = append(text, template[i])
+			}
+		}
+		addStop(index, start)
+	}
+
+	sort.SliceStable(stops, func(i, j int) bool {
+		if stops[i].Index == 0 || stops[j].Index == 0 {
+			return stops[j].Index == 0 && stops[i].Index != 0
+		}
+		return stops[i].Index < stops[j].Index
+	})
+	return text, stops
+}
+
+// SnippetCandidate returns a candidate that expands template with ParseSnippet.
+func SnippetCandidate(name string, template string) Candidate {
+	return Candidate{
+		Name: []rune(name + " "),
+		Insertion: &Insertion{
+			Text:    []rune(template),
+			Snippet: true,
+		},
+	}
+}
+
+type opSnippet struct {
+	op *Operation
+
+	inMode  bool
+	stops   []SnippetStop
+	current int
+	edited  bool
+	bufLen  int
+	bufPos  int
+}
+
+func newOpSnippet(op *Operation) *opSnippet {
+	return &opSnippet{op: op}
+}
+
+func (o *opSnippet) IsSnippetMode() bool {
+	return o.inMode
+}
+
+// EnterSnippetMode starts a session with the stops of an inserted snippet.
+// The offsets of the stops are positions in the buffer.
+func (o *opSnippet) EnterSnippetMode(stops []SnippetStop) {
+	if len(stops) < 1 {
+		return
+	}
+	o.stops = stops
+	o.inMode = true
+	o.selectStop(0)
+}
+
+func (o *opSnippet) ExitSnippetMode() {
+	if !o.inMode {
+		return
+	}
+	o.inMode = false
+	o.stops = nil
+	o.op.buf.ClearHighlight()
+}
+
+func (o *opSnippet) selectStop(i int) {
+	o.current = i
+	o.edited = false
+	stop := o.stops[i]
+	if stop.Index == 0 {
+		// the final position
+		o.op.buf.SetWithIdx(stop.End, o.op.buf.Runes())
+		o.ExitSnippetMode()
+		return
+	}
+	o.op.buf.SetHighlight(stop.Start, stop.End, "7")
+	o.op.buf.SetWithIdx(stop.End, o.op.buf.Runes())
+	o.bufLen, o.bufPos = o.op.buf.Len(), o.op.buf.Pos()
+}
+
+// HandleSnippet handles the keys moving between stops.
+// It returns true if r is consumed.
+func (o *opSnippet) HandleSnippet(r rune) bool {
+	switch r {
+	case CharTab:
+		if o.current+1 < len(o.stops) {
+			o.selectStop(o.current + 1)
+		} else {
+			o.ExitSnippetMode()
+		}
+		return true
+	case CharShiftTab:
+		if 0 < o.current {
+			o.selectStop(o.current - 1)
+		} else {
+			o.op.t.Bell()
+		}
+		return true
+	case CharEnter, CharCtrlJ, CharInterrupt, CharBell:
+		o.ExitSnippetMode()
+		return false
+	}
+
+	stop := o.stops[o.current]
+	pos := o.op.buf.Pos()
+	if !o.edited && IsPrintable(r) && stop.Start <= pos && pos <= stop.End {
+		// typing in the placeholder replaces the default text
+		rs := o.op.buf.Runes()
+		rs = append(rs[:stop.Start], rs[stop.End:]...)
+		o.op.buf.SetWithIdx(stop.Start, rs)
+		o.shift(stop.Start, stop.Start-stop.End)
+		o.bufLen, o.bufPos = o.op.buf.Len(), o.op.buf.Pos()
+	}
+	return false
+}
+
+// shift moves the stops by the edit at pos that changes the length of the
+// buffer by delta, regardless of their indices. The text inserted at the
+// edges of the current stop is a part of it.
+func (o *opSnippet) shift(pos int, delta int) {
+	deleted := func(x int) int {
+		if x <= pos {
+			return x
+		}
+		if x <= pos-delta {
+			return pos
+		}
+		return x + delta
+	}
+	for i := range o.stops {
+		stop := &o.stops[i]
+		switch {
+		case delta < 0:
+			stop.Start, stop.End = deleted(stop.Start), deleted(stop.End)
+		case i == o.current && stop.Start <= pos && pos <= stop.End, stop.Start < pos && pos < stop.End:
+			stop.End += delta
+		case pos <= stop.Start:
+			stop.Start += delta
+			stop.End += delta
+		}
+	}
+}
+
+// SnippetUpdate follows the edit made by the last key.
+// The session goes on while the cursor is out of the current placeholder,
+// until Tab is pressed at the last stop or the session is canceled.
+func (o *opSnippet) SnippetUpdate() {
+	delta := o.op.buf.Len() - o.bufLen
+	pos := o.op.buf.Pos()
+	at := o.bufPos
+	if pos < at {
+		at = pos
+	}
+	o.bufLen, o.bufPos = o.op.buf.Len(), pos
+
+	if delta != 0 {
+		stop := o.stops[o.current]
+		if stop.Start <= at && at <= stop.End {
+			o.edited = true
+		}
+		o.shift(at, delta)
+	}
+	for _, stop := range o.stops {
+		if stop.Start < 0 || o.op.buf.Len() < stop.End {
+			// the line is replaced
+			o.ExitSnippetMode()
+			return
+		}
+	}
+
+	stop := o.stops[o.current]
+	if o.edited {
+		o.op.buf.SetHighlight(stop.Start, stop.End, "4")
+	} else {
+		o.op.buf.SetHighlight(stop.Start, stop.End, "7")
+	}
+}
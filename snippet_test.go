@@ -0,0 +1,162 @@
+package readline
+
+import (
+	"reflect"
+	"testing"
+)
+
+var parseSnippetTests = []struct {
+	Input  string
+	Expect string
+	Stops  []SnippetStop
+}{
+	{
+		Input:  "SELECT ${1:cols} FROM ${2:table} WHERE ${3}",
+		Expect: "SELECT cols FROM table WHERE ",
+		Stops: []SnippetStop{
+			{Index: 1, Start: 7, End: 11},
+			{Index: 2, Start: 17, End: 22},
+			{Index: 3, Start: 29, End: 29},
+		},
+	},
+	{
+		Input:  "$0($2, ${1:a\\}b})",
+		Expect: "(, a}b)",
+		Stops: []SnippetStop{
+			{Index: 1, Start: 3, End: 6},
+			{Index: 2, Start: 1, End: 1},
+			{Index: 0, Start: 0, End: 0},
+		},
+	},
+	{
+		Input:  "\\$1 $x ${y} ${1:dup}",
+		Expect: "$1 $x ${y} dup",
+		Stops: []SnippetStop{
+			{Index: 1, Start: 11, End: 14},
+		},
+	},
+}
+
+func TestParseSnippet(t *testing.T) {
+	for _, v := range parseSnippetTests {
+		text, stops := ParseSnippet([]rune(v.Input))
+		if string(text) != v.Expect {
+			t.Errorf("result = %q, want %q for %q", string(text), v.Expect, v.Input)
+		}
+		if !reflect.DeepEqual(stops, v.Stops) {
+			t.Errorf("stops = %v, want %v for %q", stops, v.Stops, v.Input)
+		}
+	}
+}
+
+func TestRuneBuffer_InsertCandidate_Snippet(t *testing.T) {
+	buf := new(RuneBuffer)
+	buf.WriteString("-- sel")
+	stops := buf.InsertCandidate(SnippetCandidate("select", "SELECT ${1:cols} FROM ${2:table}"), 3)
+
+	expect := "-- SELECT cols FROM table"
+	if string(buf.Runes()) != expect {
+		t.Errorf("result = %q, want %q", string(buf.Runes()), expect)
+	}
+	expectStops := []SnippetStop{
+		{Index: 1, Start: 10, End: 14},
+		{Index: 2, Start: 20, End: 25},
+	}
+	if !reflect.DeepEqual(stops, expectStops) {
+		t.Errorf("stops = %v, want %v", stops, expectStops)
+	}
+}
+
+var snippetEditTests = []struct {
+	Template string
+	Keys     []rune
+	Expect   string
+	Selected string
+	Idx      int
+}{
+	{
+		Template: "SELECT ${1:a}, $0 ${2:b}",
+		Keys:     []rune("xyz\t"),
+		Expect:   "SELECT xyz,  b",
+		Selected: "b",
+		Idx:      14,
+	},
+	{
+		Template: "SELECT ${1:a}, $0 ${2:b}",
+		Keys:     []rune("xyz\tq\t"),
+		Expect:   "SELECT xyz,  q",
+		Idx:      12,
+	},
+	{
+		Template: "${2:a} ${1:b}",
+		Keys:     []rune("xyz\t"),
+		Expect:   "a xyz",
+		Selected: "a",
+		Idx:      1,
+	},
+	{
+		Template: "${2:a} ${1:b}",
+		Keys:     []rune{'\t', 'q', 'q', CharShiftTab},
+		Expect:   "qq b",
+		Selected: "b",
+		Idx:      4,
+	},
+	{
+		Template: "SELECT ${1:a} FROM ${2:t}",
+		Keys:     []rune{CharLineStart, '-', '-', ' ', '\t'},
+		Expect:   "-- SELECT a FROM t",
+		Selected: "t",
+		Idx:      18,
+	},
+	{
+		Template: "x ${1:a} ${2:b}",
+		Keys:     []rune{'y', CharLineStart, CharDelete, '\t', 'z', CharShiftTab},
+		Expect:   " y z",
+		Selected: "y",
+		Idx:      2,
+	},
+	{
+		Template: "${1:a} ${2:b}",
+		Keys:     []rune{CharLineStart, CharBell, 'z'},
+		Expect:   "za b",
+		Idx:      1,
+	},
+}
+
+func TestOpSnippet_Edit(t *testing.T) {
+	for _, v := range snippetEditTests {
+		op := &Operation{buf: new(RuneBuffer)}
+		o := newOpSnippet(op)
+		o.EnterSnippetMode(op.buf.InsertCandidate(SnippetCandidate("s", v.Template), 0))
+		for _, r := range v.Keys {
+			if o.IsSnippetMode() && o.HandleSnippet(r) {
+				continue
+			}
+			switch r {
+			case CharLineStart:
+				op.buf.MoveToLineStart()
+			case CharDelete:
+				op.buf.Delete()
+			case CharBell:
+			default:
+				op.buf.WriteRune(r)
+			}
+			if o.IsSnippetMode() {
+				o.SnippetUpdate()
+			}
+		}
+
+		rs := op.buf.Runes()
+		if string(rs) != v.Expect || op.buf.Pos() != v.Idx {
+			t.Errorf("result = %q (%d), want %q (%d) for %q", string(rs), op.buf.Pos(), v.Expect, v.Idx, v.Template)
+		}
+		selected := ""
+		if o.IsSnippetMode() {
+			stop := o.stops[o.current]
+			selected = string(rs[stop.Start:stop.End])
+		}
+		if selected != v.Selected {
+			t.Errorf("selected = %q, want %q for %q", selected, v.Selected, v.Template)
+		}
+	}
+}
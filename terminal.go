@@ -10,29 +10,44 @@ import (
 )
 
 type Terminal struct {
-	m         sync.Mutex
-	cfg       *Config
-	outchan   chan rune
-	closed    int32
-	stopChan  chan struct{}
-	kickChan  chan struct{}
-	wg        sync.WaitGroup
-	isReading int32
-	sleeping  int32
+	m          sync.Mutex
+	cfg        *Config
+	outchan    chan rune
+	closed     int32
+	stopChan   chan struct{}
+	kickChan   chan struct{}
+	resumeChan chan struct{}
+	pauseNext  int32
+	wg         sync.WaitGroup
+	isReading  int32
+	sleeping   int32
+
+	cursorShape int32
 
 	sizeChan chan string
 }
 
+// CursorShape is a cursor style of DECSCUSR.
+type CursorShape int32
+
+const (
+	CursorDefault   CursorShape = 0
+	CursorBlock     CursorShape = 2
+	CursorUnderline CursorShape = 4
+	CursorBar       CursorShape = 6
+)
+
 func NewTerminal(cfg *Config) (*Terminal, error) {
 	if err := cfg.Init(); err != nil {
 		return nil, err
 	}
 	t := &Terminal{
-		cfg:      cfg,
-		kickChan: make(chan struct{}, 1),
-		outchan:  make(chan rune),
-		stopChan: make(chan struct{}, 1),
-		sizeChan: make(chan string, 1),
+		cfg:        cfg,
+		kickChan:   make(chan struct{}, 1),
+		resumeChan: make(chan struct{}, 1),
+		outchan:    make(chan rune),
+		stopChan:   make(chan struct{}, 1),
+		sizeChan:   make(chan string, 1),
 	}
 
 	go t.ioloop()
@@ -97,11 +112,22 @@ func (t *Terminal) Readline() *Operation {
 
 // ReadRune returns rune(0) if meet EOF
 func (t *Terminal) ReadRune() rune {
-	ch, ok := <-t.outchan
-	if !ok {
-		return rune(0)
+	r, _ := t.readRuneOr(nil)
+	return r
+}
+
+// readRuneOr reads a rune like ReadRune, or returns false when cancel is
+// signalled. The key being read is returned by the next call.
+func (t *Terminal) readRuneOr(cancel <-chan struct{}) (rune, bool) {
+	select {
+	case ch, ok := <-t.outchan:
+		if !ok {
+			return rune(0), true
+		}
+		return ch, true
+	case <-cancel:
+		return rune(0), false
 	}
-	return ch
 }
 
 func (t *Terminal) IsReading() bool {
@@ -115,6 +141,38 @@ func (t *Terminal) KickRead() {
 	}
 }
 
+// PauseAfterNext stops reading stdin after the next key is read, until
+// Resume is called. It is used before a key that may run a program reading
+// stdin, such as an external editor, so that no input is taken from it.
+func (t *Terminal) PauseAfterNext() {
+	atomic.StoreInt32(&t.pauseNext, 1)
+}
+
+// Resume restarts reading stdin stopped by PauseAfterNext.
+func (t *Terminal) Resume() {
+	if atomic.CompareAndSwapInt32(&t.pauseNext, 1, 0) {
+		// no key has been read since PauseAfterNext
+		return
+	}
+	select {
+	case t.resumeChan <- struct{}{}:
+	default:
+	}
+}
+
+// pause waits for Resume if PauseAfterNext is called.
+func (t *Terminal) pause() bool {
+	if !atomic.CompareAndSwapInt32(&t.pauseNext, 1, 0) {
+		return true
+	}
+	select {
+	case <-t.resumeChan:
+		return true
+	case <-t.stopChan:
+		return false
+	}
+}
+
 func (t *Terminal) ioloop() {
 	t.wg.Add(1)
 	defer func() {
@@ -141,6 +199,7 @@ func (t *Terminal) ioloop() {
 			}
 		}
 		expectNextChar = false
+
 		r, _, err := buf.ReadRune()
 		if err != nil {
 			if strings.Contains(err.Error(), "interrupted system call") {
@@ -150,6 +209,11 @@ func (t *Terminal) ioloop() {
 			break
 		}
 
+		if r == 0 {
+			// Ctrl+Space sends NUL, and 0 means io.EOF.
+			r = CharCtrlSpace
+		}
+
 		if isEscape {
 			isEscape = false
 			if r == CharEscapeEx {
@@ -200,6 +264,9 @@ func (t *Terminal) ioloop() {
 		case CharEsc:
 			if t.cfg.VimMode {
 				t.outchan <- r
+				if !t.pause() {
+					return
+				}
 				break
 			}
 			isEscape = true
@@ -208,6 +275,9 @@ func (t *Terminal) ioloop() {
 			fallthrough
 		default:
 			t.outchan <- r
+			if !t.pause() {
+				return
+			}
 		}
 	}
 
@@ -217,10 +287,19 @@ func (t *Terminal) Bell() {
 	_, _ = fmt.Fprintf(t, "%c", CharBell)
 }
 
+// SetCursorShape changes the shape of the cursor with DECSCUSR.
+func (t *Terminal) SetCursorShape(shape CursorShape) {
+	if CursorShape(atomic.SwapInt32(&t.cursorShape, int32(shape))) == shape {
+		return
+	}
+	_, _ = fmt.Fprintf(t, "\033[%d q", shape)
+}
+
 func (t *Terminal) Close() error {
 	if atomic.SwapInt32(&t.closed, 1) != 0 {
 		return nil
 	}
+	t.SetCursorShape(CursorDefault)
 	if closer, ok := t.cfg.Stdin.(io.Closer); ok {
 		_ = closer.Close()
 	}
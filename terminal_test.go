@@ -0,0 +1,63 @@
+package readline
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+func TestTerminal_ReadRune(t *testing.T) {
+	r, w := io.Pipe()
+	term, err := NewTerminal(&Config{Stdin: r, Stdout: io.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer term.Close()
+	defer w.Close()
+
+	go func() {
+		w.Write([]byte("ab\033["))
+		time.Sleep(10 * time.Millisecond)
+		w.Write([]byte("Ac"))
+	}()
+	term.KickRead()
+	expect := []rune{'a', 'b', CharPrev, 'c'}
+	for _, e := range expect {
+		if r := term.ReadRune(); r != e {
+			t.Errorf("result = %q, want %q", r, e)
+		}
+	}
+}
+
+func TestTerminal_PauseAfterNext(t *testing.T) {
+	r, w := io.Pipe()
+	term, err := NewTerminal(&Config{Stdin: r, Stdout: io.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer term.Close()
+	defer w.Close()
+
+	term.KickRead()
+	term.PauseAfterNext()
+	go w.Write([]byte("a"))
+	if r := term.ReadRune(); r != 'a' {
+		t.Errorf("result = %q, want %q", r, 'a')
+	}
+
+	written := make(chan struct{})
+	go func() {
+		w.Write([]byte("b"))
+		close(written)
+	}()
+	select {
+	case <-written:
+		t.Errorf("stdin is read while the terminal is paused")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	term.Resume()
+	if r := term.ReadRune(); r != 'b' {
+		t.Errorf("result = %q, want %q", r, 'b')
+	}
+}
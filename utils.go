@@ -40,9 +40,11 @@ const (
 	CharTranspose = 20
 	CharCtrlU     = 21
 	CharCtrlW     = 23
+	CharCtrlX     = 24
 	CharCtrlY     = 25
 	CharCtrlZ     = 26
 	CharEsc       = 27
+	CharFindChar  = 29
 	CharO         = 79
 	CharEscapeEx  = 91
 	CharBackspace = 127
@@ -54,8 +56,42 @@ const (
 	MetaDelete
 	MetaBackspace
 	MetaTranspose
+	CharShiftTab
+	MetaUpcase
+	MetaDowncase
+	MetaCapitalize
+	MetaMinus
+	MetaDigit0
+	MetaDigit1
+	MetaDigit2
+	MetaDigit3
+	MetaDigit4
+	MetaDigit5
+	MetaDigit6
+	MetaDigit7
+	MetaDigit8
+	MetaDigit9
+	CharCtrlSpace
+	MetaCopy
+	MetaFindCharBackward
+	CharPageUp
+	CharPageDown
+	MetaYankLastArg
+	MetaYankNthArg
 )
 
+// modifier keys encoded in the escape sequences, such as "\033[1;5D"
+const (
+	modShift = 1 << iota
+	modAlt
+	modCtrl
+)
+
+// IsMetaDigit reports whether r is one of Meta+0 to Meta+9.
+func IsMetaDigit(r rune) bool {
+	return MetaDigit9 <= r && r <= MetaDigit0
+}
+
 // WaitForResume need to call before current process got suspend.
 // It will run a ticker until a long duration is occurs,
 // which means this process is resumed.
@@ -102,11 +138,18 @@ func IsPrintable(key rune) bool {
 // translate Esc[X
 func escapeExKey(key *escapeKeyPair) rune {
 	var r rune
+	mod := key.modifier()
 	switch key.typ {
 	case 'D':
 		r = CharBackward
+		if mod&(modAlt|modCtrl) != 0 {
+			r = MetaBackward
+		}
 	case 'C':
 		r = CharForward
+		if mod&(modAlt|modCtrl) != 0 {
+			r = MetaForward
+		}
 	case 'A':
 		r = CharPrev
 	case 'B':
@@ -115,9 +158,23 @@ func escapeExKey(key *escapeKeyPair) rune {
 		r = CharLineStart
 	case 'F':
 		r = CharLineEnd
+	case 'Z':
+		r = CharShiftTab
 	case '~':
-		if key.attr == "3" {
+		switch key.code() {
+		case "1", "7":
+			r = CharLineStart
+		case "4", "8":
+			r = CharLineEnd
+		case "3":
 			r = CharDelete
+			if mod&(modAlt|modCtrl) != 0 {
+				r = MetaDelete
+			}
+		case "5":
+			r = CharPageUp
+		case "6":
+			r = CharPageDown
 		}
 	default:
 	}
@@ -150,6 +207,28 @@ type escapeKeyPair struct {
 	typ  rune
 }
 
+// code returns the first parameter of the sequence, such as "5" of "5~".
+func (e *escapeKeyPair) code() string {
+	if i := strings.IndexByte(e.attr, ';'); -1 < i {
+		return e.attr[:i]
+	}
+	return e.attr
+}
+
+// modifier returns the modifier keys of the sequence as the bits of
+// modShift, modAlt and modCtrl.
+func (e *escapeKeyPair) modifier() int {
+	i := strings.IndexByte(e.attr, ';')
+	if i < 0 {
+		return 0
+	}
+	m, err := strconv.Atoi(e.attr[i+1:])
+	if err != nil || m < 1 {
+		return 0
+	}
+	return m - 1
+}
+
 func (e *escapeKeyPair) Get2() (int, int, bool) {
 	sp := strings.Split(e.attr, ";")
 	if len(sp) < 2 {
@@ -192,10 +271,28 @@ func escapeKey(r rune, reader *bufio.Reader) rune {
 		r = MetaForward
 	case 'd':
 		r = MetaDelete
-	case CharTranspose:
+	case 't', CharTranspose:
 		r = MetaTranspose
+	case 'u':
+		r = MetaUpcase
+	case 'l':
+		r = MetaDowncase
+	case 'c':
+		r = MetaCapitalize
 	case CharBackspace:
 		r = MetaBackspace
+	case 'w':
+		r = MetaCopy
+	case CharFindChar:
+		r = MetaFindCharBackward
+	case '.', '_':
+		r = MetaYankLastArg
+	case CharCtrlY:
+		r = MetaYankNthArg
+	case '-':
+		r = MetaMinus
+	case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		r = MetaDigit0 - (r - '0')
 	case 'O':
 		d, _, _ := reader.ReadRune()
 		switch d {
@@ -1 +1,35 @@
 package readline
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+var escapeExKeyTests = []struct {
+	Seq    string
+	Expect rune
+}{
+	{Seq: "D", Expect: CharBackward},
+	{Seq: "1;5D", Expect: MetaBackward},
+	{Seq: "1;3C", Expect: MetaForward},
+	{Seq: "1;2C", Expect: CharForward},
+	{Seq: "1;5A", Expect: CharPrev},
+	{Seq: "3~", Expect: CharDelete},
+	{Seq: "3;5~", Expect: MetaDelete},
+	{Seq: "5~", Expect: CharPageUp},
+	{Seq: "6~", Expect: CharPageDown},
+	{Seq: "1~", Expect: CharLineStart},
+	{Seq: "4~", Expect: CharLineEnd},
+	{Seq: "2~", Expect: 0},
+}
+
+func TestEscapeExKey(t *testing.T) {
+	for _, v := range escapeExKeyTests {
+		reader := bufio.NewReader(strings.NewReader(v.Seq[1:]))
+		result := escapeExKey(readEscKey(rune(v.Seq[0]), reader))
+		if result != v.Expect {
+			t.Errorf("result = %d, want %d for %q", result, v.Expect, v.Seq)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package readline
+
+import "errors"
+
+// validationStyle is the SGR style of the validation error.
+const validationStyle = "31"
+
+// ValidationError is returned by Config.Validator to report the position
+// of the error in the line.
+type ValidationError struct {
+	// Pos is the rune index where the cursor moves.
+	Pos int
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// validate checks the line with Config.Validator. If the line is invalid,
+// it keeps the error to be shown as the hint and returns false.
+// A line continued by a trailing backslash is not checked, and the line
+// ending it is checked with the continued lines joined before it.
+func (o *Operation) validate() bool {
+	cfg := o.GetConfig()
+	if cfg.Validator == nil {
+		return true
+	}
+
+	line := o.buf.Runes()
+	if !cfg.UniqueEditLine && o.isContinued() {
+		return true
+	}
+	err := cfg.Validator(append(runes.Copy(o.continuousBuf), line...))
+	if err == nil {
+		return true
+	}
+
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		// the position is in the joined line
+		pos := verr.Pos - len(o.continuousBuf)
+		if pos < 0 {
+			pos = 0
+		} else if len(line) < pos {
+			pos = len(line)
+		}
+		o.buf.SetWithIdx(pos, line)
+	}
+	o.m.Lock()
+	o.invalid, o.invalidMsg = line, err.Error()
+	o.m.Unlock()
+	o.t.KickRead()
+	return false
+}
+
+// isContinued reports whether the line is continued to the next line,
+// that is, the line or the text before the cursor ends with a backslash.
+func (o *Operation) isContinued() bool {
+	line, pos := o.buf.Runes(), o.buf.Pos()
+	return 0 < pos && line[pos-1] == '\\' || 0 < len(line) && line[len(line)-1] == '\\'
+}
@@ -1,5 +1,11 @@
 package readline
 
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
 const (
 	VIM_NORMAL = iota
 	VIM_INSERT
@@ -10,6 +16,33 @@ type opVim struct {
 	cfg     *Config
 	op      *Operation
 	vimMode int
+
+	visualStart int
+	// visualLine is true in the linewise visual mode
+	visualLine bool
+
+	lastFindKind rune
+	lastFindChar rune
+
+	register  rune
+	registers map[rune][]rune
+
+	lastChange      []rune
+	lastChangeCount int
+	changeKeys      []rune
+	changeCount     int
+	inserting       bool
+
+	pending   []rune
+	macroReg  rune
+	macro     []rune
+	lastMacro rune
+}
+
+// Clipboard gives access to the clipboard of the host.
+type Clipboard interface {
+	ReadClipboard() ([]rune, error)
+	WriteClipboard(text []rune) error
 }
 
 func newVimMode(op *Operation) *opVim {
@@ -26,151 +59,711 @@ func (o *opVim) SetVimMode(on bool) {
 		o.ExitVimMode()
 	}
 	o.cfg.VimMode = on
-	o.vimMode = VIM_INSERT
+	o.setMode(VIM_INSERT)
+	o.op.buf.SetPrompt(o.VimPrompt(o.op.prompt))
 }
 
 func (o *opVim) ExitVimMode() {
-	o.vimMode = VIM_INSERT
+	if o.vimMode == VIM_VISUAL {
+		o.op.buf.ClearHighlight()
+	}
+	o.setMode(VIM_INSERT)
+}
+
+// setMode switches the vim mode and updates the mode indicator and the
+// cursor shape.
+func (o *opVim) setMode(mode int) {
+	changed := o.vimMode != mode
+	o.vimMode = mode
+
+	if o.cfg.VimCursorShape && o.op.t != nil {
+		shape := CursorDefault
+		if o.IsEnableVimMode() {
+			shape = CursorBlock
+			if mode == VIM_INSERT {
+				shape = CursorBar
+			}
+		}
+		o.op.t.SetCursorShape(shape)
+	}
+	if changed && (o.cfg.VimModePrefix != nil || o.cfg.FuncVimModePrompt != nil) {
+		o.op.buf.UpdatePrompt(o.VimPrompt(o.op.prompt))
+	}
+}
+
+// VimPrompt returns the prompt with the indicator of the vim mode.
+func (o *opVim) VimPrompt(prompt string) string {
+	if !o.IsEnableVimMode() {
+		return prompt
+	}
+	if o.cfg.FuncVimModePrompt != nil {
+		return o.cfg.FuncVimModePrompt(o.vimMode, prompt)
+	}
+	return o.cfg.VimModePrefix[o.vimMode] + prompt
 }
 
 func (o *opVim) IsEnableVimMode() bool {
 	return o.cfg.VimMode
 }
 
-func (o *opVim) handleVimNormalMovement(r rune, readNext func() rune) (t rune, handled bool) {
-	rb := o.op.buf
-	handled = true
+// ReadVimRune returns the next key replayed by a macro or ".".
+// Otherwise it reads a key with read, and records it while a macro
+// is recorded.
+func (o *opVim) ReadVimRune(read func() rune) rune {
+	if 0 < len(o.pending) {
+		r := o.pending[0]
+		o.pending = o.pending[1:]
+		return r
+	}
+	r := read()
+	if o.macroReg != 0 && r != charCancel {
+		o.macro = append(o.macro, r)
+	}
+	return r
+}
+
+func (o *opVim) replay(keys []rune) {
+	o.pending = append(append([]rune{}, keys...), o.pending...)
+}
+
+func isVimRegister(r rune) bool {
+	return ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || r == '+' || r == '"'
+}
+
+// readRegister returns the text of the selected register.
+// The unnamed register is the text saved for Yank.
+func (o *opVim) readRegister() []rune {
+	switch reg := o.register; {
+	case reg == 0 || reg == '"':
+		return o.op.buf.killed()
+	case reg == '+' && o.cfg.Clipboard != nil:
+		text, err := o.cfg.Clipboard.ReadClipboard()
+		if err != nil {
+			o.bell()
+			return nil
+		}
+		return text
+	default:
+		return o.registers[unicode.ToLower(reg)]
+	}
+}
+
+// writeRegister stores text in the selected register.
+// An upper case register appends text to the register.
+func (o *opVim) writeRegister(text []rune) {
+	if o.registers == nil {
+		o.registers = make(map[rune][]rune)
+	}
+	switch reg := o.register; {
+	case reg == 0 || reg == '"':
+	case reg == '+' && o.cfg.Clipboard != nil:
+		if err := o.cfg.Clipboard.WriteClipboard(text); err != nil {
+			o.bell()
+		}
+	case unicode.IsUpper(reg):
+		lower := unicode.ToLower(reg)
+		o.registers[lower] = append(o.registers[lower], text...)
+	default:
+		o.registers[reg] = append([]rune{}, text...)
+	}
+}
+
+// bell rings the bell unless the read is canceled, in which case the
+// command is abandoned silently.
+func (o *opVim) bell() {
+	if !o.op.isCanceled() {
+		o.op.t.Bell()
+	}
+}
+
+// readCount reads the count prefixed to a command.
+// It returns 0 if no count is given.
+func (o *opVim) readCount(r rune, readNext func() rune) (int, rune) {
+	count := 0
+	for ('1' <= r && r <= '9') || (0 < count && r == '0') {
+		count = count*10 + int(r-'0')
+		r = readNext()
+	}
+	return count, r
+}
+
+func vimCount(count int) int {
+	if count < 1 {
+		return 1
+	}
+	return count
+}
+
+// motion returns the position reached by the motion r from pos.
+// inclusive reports whether an operator covers the rune at the target.
+func (o *opVim) motion(r rune, count int, pos int, readNext func() rune) (target int, inclusive bool, ok bool) {
+	rs := o.op.buf.Runes()
+	target = pos
+	ok = true
 	switch r {
 	case 'h':
-		t = CharBackward
-	case 'j':
-		t = CharNext
-	case 'k':
-		t = CharPrev
-	case 'l':
-		t = CharForward
-	case '0', '^':
-		rb.MoveToLineStart()
+		target = pos - count
+		if target < 0 {
+			target = 0
+		}
+	case 'l', ' ':
+		target = pos + count
+		if len(rs) < target {
+			target = len(rs)
+		}
+	case '0':
+		target = 0
+	case '^':
+		target = vimFirstNonBlank(rs)
 	case '$':
-		rb.MoveToLineEnd()
-	case 'x':
-		rb.Delete()
-		if rb.IsCursorInEnd() {
-			rb.MoveBackward()
+		if 0 < len(rs) {
+			target = len(rs) - 1
+			inclusive = true
 		}
-	case 'r':
-		rb.Replace(readNext())
-	case 'd':
-		next := readNext()
-		switch next {
-		case 'd':
-			rb.Erase()
-		case 'w':
-			rb.DeleteWord()
-		case 'h':
-			rb.Backspace()
-		case 'l':
-			rb.Delete()
-		}
-	case 'p':
-		rb.Yank()
-	case 'b', 'B':
-		rb.MoveToPrevWord()
 	case 'w', 'W':
-		rb.MoveToNextWord()
+		classes := o.wordClasses(rs, r == 'W')
+		for i := 0; i < count; i++ {
+			target = vimNextWordStart(classes, target)
+		}
+	case 'b', 'B':
+		classes := o.wordClasses(rs, r == 'B')
+		for i := 0; i < count; i++ {
+			target = vimPrevWordStart(classes, target)
+		}
 	case 'e', 'E':
-		rb.MoveToEndWord()
+		if len(rs) < 1 {
+			return pos, false, false
+		}
+		classes := o.wordClasses(rs, r == 'E')
+		for i := 0; i < count; i++ {
+			target = vimWordEnd(classes, target)
+		}
+		inclusive = true
 	case 'f', 'F', 't', 'T':
-		next := readNext()
-		prevChar := r == 't' || r == 'T'
-		reverse := r == 'F' || r == 'T'
-		switch next {
-		case CharEsc:
-		default:
-			rb.MoveTo(next, prevChar, reverse)
+		ch := readNext()
+		if ch == CharEsc || ch == charCancel {
+			return pos, false, false
+		}
+		o.lastFindKind, o.lastFindChar = r, ch
+		return o.findChar(rs, pos, r, ch, count, false)
+	case ';', ',':
+		if o.lastFindKind == 0 {
+			return pos, false, false
 		}
+		kind := o.lastFindKind
+		if r == ',' {
+			switch kind {
+			case 'f':
+				kind = 'F'
+			case 'F':
+				kind = 'f'
+			case 't':
+				kind = 'T'
+			case 'T':
+				kind = 't'
+			}
+		}
+		return o.findChar(rs, pos, kind, o.lastFindChar, count, true)
 	default:
-		return r, false
+		ok = false
+	}
+	return
+}
+
+// wordClasses classifies rs for word motions. WORD motions always use
+// VimWORDs, and word motions use Config.WordClassifier if it is set.
+func (o *opVim) wordClasses(rs []rune, bigWord bool) []int {
+	switch {
+	case bigWord:
+		return VimWORDs.Classify(rs)
+	case o.cfg.WordClassifier != nil:
+		return o.cfg.WordClassifier.Classify(rs)
+	}
+	return VimWords.Classify(rs)
+}
+
+func (o *opVim) findChar(rs []rune, pos int, kind rune, ch rune, count int, repeat bool) (int, bool, bool) {
+	target := pos
+	for i := 0; i < count; i++ {
+		next, found := vimFindChar(rs, target, kind, ch, repeat || 0 < i)
+		if !found {
+			return pos, false, false
+		}
+		target = next
+	}
+	return target, kind == 'f' || kind == 't', true
+}
+
+// changeWordEnd returns the end of the range changed by "cw".
+// Like vim, "cw" on a word changes to the end of the word.
+func changeWordEnd(classes []int, pos int, count int) int {
+	target := pos
+	for i := 0; i < count; i++ {
+		if i == 0 && (target+1 == len(classes) || classes[target+1] != classes[target]) {
+			continue
+		}
+		target = vimWordEnd(classes, target)
+	}
+	return target + 1
+}
+
+func (o *opVim) handleVimOperator(op rune, count int, readNext func() rune) {
+	rb := o.op.buf
+	rs := rb.Runes()
+	pos := rb.Pos()
+	n, r := o.readCount(readNext(), readNext)
+	count = vimCount(count) * vimCount(n)
+
+	var start, end int
+	switch {
+	case r == op:
+		start, end = 0, len(rs)
+		if op == 'y' {
+			o.copyRange(start, end)
+			return
+		}
+	case r == 'i' || r == 'a':
+		var ok bool
+		key := readNext()
+		start, end, ok = vimTextObject(rs, o.wordClasses(rs, key == 'W'), pos, key, r == 'a')
+		if !ok {
+			o.bell()
+			return
+		}
+	case op == 'c' && (r == 'w' || r == 'W') && pos < len(rs) && !unicode.IsSpace(rs[pos]):
+		start, end = pos, changeWordEnd(o.wordClasses(rs, r == 'W'), pos, count)
+	default:
+		target, inclusive, ok := o.motion(r, count, pos, readNext)
+		if !ok {
+			o.bell()
+			return
+		}
+		start, end = pos, target
+		if end < start {
+			start, end = end, start
+		}
+		if inclusive {
+			end++
+		}
+	}
+	o.applyOperator(op, start, end)
+}
+
+// applyOperator applies the operator d, c or y to the range.
+// The text is saved for Yank and in the selected register.
+func (o *opVim) applyOperator(op rune, start, end int) {
+	rb := o.op.buf
+	switch op {
+	case 'd':
+		rb.KillRange(start, end)
+		o.writeRegister(rb.killed())
+		o.fixNormalPos()
+	case 'c':
+		rb.KillRange(start, end)
+		o.writeRegister(rb.killed())
+		o.EnterVimInsertMode()
+	case 'y':
+		o.copyRange(start, end)
+		rb.SetPos(start)
+		o.fixNormalPos()
+	}
+}
+
+func (o *opVim) copyRange(start, end int) {
+	o.op.buf.CopyRange(start, end)
+	o.writeRegister(o.op.buf.killed())
+}
+
+// fixNormalPos keeps the cursor on a rune in normal mode.
+func (o *opVim) fixNormalPos() {
+	if o.op.buf.IsCursorInEnd() && 0 < o.op.buf.Len() {
+		o.op.buf.MoveBackward()
+	}
+}
+
+func (o *opVim) paste(after bool, count int) {
+	text := o.readRegister()
+	if len(text) < 1 {
+		return
+	}
+	rb := o.op.buf
+	rs := rb.Runes()
+	pos := rb.Pos()
+	if after && pos < len(rs) {
+		pos++
+	}
+	buf := make([]rune, 0, len(rs)+len(text)*count)
+	buf = append(buf, rs[:pos]...)
+	for i := 0; i < count; i++ {
+		buf = append(buf, text...)
+	}
+	buf = append(buf, rs[pos:]...)
+	rb.SetWithIdx(pos+len(text)*count-1, buf)
+}
+
+func (o *opVim) recordMacro(readNext func() rune) {
+	if o.macroReg != 0 {
+		macro := o.macro
+		if 0 < len(macro) && macro[len(macro)-1] == 'q' {
+			// the key stopping the recording
+			macro = macro[:len(macro)-1]
+		}
+		o.register = o.macroReg
+		o.writeRegister(macro)
+		o.macroReg, o.macro = 0, nil
+		return
+	}
+
+	reg := readNext()
+	if !isVimRegister(reg) || reg == '"' || reg == '+' {
+		o.bell()
+		return
+	}
+	o.macroReg = reg
+}
+
+func (o *opVim) playMacro(count int, readNext func() rune) {
+	reg := readNext()
+	if reg == '@' {
+		reg = o.lastMacro
+	}
+	if !isVimRegister(reg) || reg == '"' {
+		o.bell()
+		return
+	}
+	o.lastMacro = reg
+	o.register = reg
+	macro := o.readRegister()
+
+	keys := make([]rune, 0, len(macro)*count)
+	for i := 0; i < count; i++ {
+		keys = append(keys, macro...)
 	}
-	return t, true
+	o.replay(keys)
 }
 
-func (o *opVim) handleVimNormalEnterInsert(r rune, readNext func() rune) (t rune, handled bool) {
+// repeatChange replays the last change.
+// A count replaces the count of the change.
+func (o *opVim) repeatChange(count int) {
+	if o.lastChange == nil {
+		o.bell()
+		return
+	}
+	if count == 0 {
+		count = o.lastChangeCount
+	}
+	keys := o.lastChange
+	if 0 < count {
+		keys = append([]rune(strconv.Itoa(count)), keys...)
+	}
+	o.replay(keys)
+}
+
+func isVimChange(r rune) bool {
+	return strings.ContainsRune("dcxXsSDCr~pPiIaA", r)
+}
+
+func (o *opVim) replaceChars(ch rune, count int) {
 	rb := o.op.buf
-	handled = true
+	if ch == CharEsc || ch == charCancel {
+		return
+	}
+	rs := rb.Runes()
+	pos := rb.Pos()
+	if len(rs) < pos+count {
+		o.bell()
+		return
+	}
+	for i := pos; i < pos+count; i++ {
+		rs[i] = ch
+	}
+	rb.SetWithIdx(pos+count-1, rs)
+}
+
+func (o *opVim) HandleVimNormal(r rune, readNext func() rune) (t rune) {
 	switch r {
+	case CharEnter, CharInterrupt:
+		o.ExitVimMode()
+		return r
+	}
+
+	selected := false
+	defer func() {
+		if !selected {
+			o.register = 0
+		}
+	}()
+
+	rb := o.op.buf
+	count, r := o.readCount(r, readNext)
+	n := vimCount(count)
+	pos := rb.Pos()
+
+	keys := []rune{r}
+	read := readNext
+	readNext = func() rune {
+		next := read()
+		keys = append(keys, next)
+		return next
+	}
+	defer func() {
+		if !isVimChange(r) || keys[len(keys)-1] == charCancel {
+			return
+		}
+		if o.vimMode == VIM_INSERT {
+			o.changeKeys, o.changeCount, o.inserting = keys, count, true
+		} else {
+			o.lastChange, o.lastChangeCount = keys, count
+		}
+	}()
+
+	switch r {
+	case 'j':
+		return CharNext
+	case 'k':
+		return CharPrev
+	case '"':
+		if reg := readNext(); isVimRegister(reg) {
+			o.register = reg
+			selected = true
+		} else {
+			o.bell()
+		}
+	case '.':
+		o.repeatChange(count)
+	case 'q':
+		o.recordMacro(readNext)
+	case '@':
+		o.playMacro(n, readNext)
 	case 'i':
+		o.EnterVimInsertMode()
 	case 'I':
 		rb.MoveToLineStart()
+		o.EnterVimInsertMode()
 	case 'a':
 		rb.MoveForward()
+		o.EnterVimInsertMode()
 	case 'A':
 		rb.MoveToLineEnd()
-	case 's':
-		rb.Delete()
+		o.EnterVimInsertMode()
+	case 'v':
+		// v runs the external editor as in bash, and the visual mode is
+		// started by V, where v switches it to characterwise.
+		if o.op.EditExternal() == CharEnter {
+			o.ExitVimMode()
+			return CharEnter
+		}
+	case 'V':
+		o.EnterVimVisualLineMode()
+	case 'd', 'c', 'y':
+		o.handleVimOperator(r, count, readNext)
+	case 'D':
+		o.applyOperator('d', pos, rb.Len())
+	case 'C':
+		o.applyOperator('c', pos, rb.Len())
+	case 'Y':
+		o.copyRange(0, rb.Len())
 	case 'S':
-		rb.Erase()
-	case 'c':
-		next := readNext()
-		switch next {
-		case 'c':
-			rb.Erase()
-		case 'w':
-			rb.DeleteWord()
-		case 'h':
-			rb.Backspace()
-		case 'l':
-			rb.Delete()
+		o.applyOperator('c', 0, rb.Len())
+	case 'x', 's':
+		end := pos + n
+		if rb.Len() < end {
+			end = rb.Len()
+		}
+		if r == 's' {
+			o.applyOperator('c', pos, end)
+		} else if pos < end {
+			o.applyOperator('d', pos, end)
 		}
+	case 'X':
+		start := pos - n
+		if start < 0 {
+			start = 0
+		}
+		if start < pos {
+			o.applyOperator('d', start, pos)
+		}
+	case 'r':
+		o.replaceChars(readNext(), n)
+	case '~':
+		end := pos + n
+		if rb.Len() < end {
+			end = rb.Len()
+		}
+		rb.ToggleCaseRange(pos, end)
+		rb.SetPos(end)
+		o.fixNormalPos()
+	case 'p', 'P':
+		o.paste(r == 'p', n)
 	default:
-		return r, false
+		target, _, ok := o.motion(r, n, pos, readNext)
+		if !ok {
+			// invalid operation
+			o.bell()
+			return 0
+		}
+		rb.SetPos(target)
+		o.fixNormalPos()
+	}
+	return 0
+}
+
+func (o *opVim) EnterVimInsertMode() {
+	o.setMode(VIM_INSERT)
+}
+
+func (o *opVim) ExitVimInsertMode() {
+	o.setMode(VIM_NORMAL)
+}
+
+func (o *opVim) EnterVimVisualMode() {
+	o.setMode(VIM_VISUAL)
+	o.visualLine = false
+	o.visualStart = o.op.buf.Pos()
+	if o.op.buf.IsCursorInEnd() && 0 < o.visualStart {
+		o.visualStart--
+		o.op.buf.MoveBackward()
 	}
+	o.refreshVisual()
+}
 
-	o.EnterVimInsertMode()
-	return
+// EnterVimVisualLineMode starts the visual mode selecting the whole line.
+func (o *opVim) EnterVimVisualLineMode() {
+	o.EnterVimVisualMode()
+	o.visualLine = true
+	o.refreshVisual()
 }
 
-func (o *opVim) HandleVimNormal(r rune, readNext func() rune) (t rune) {
+func (o *opVim) ExitVimVisualMode() {
+	o.setMode(VIM_NORMAL)
+	o.visualLine = false
+	o.op.buf.ClearHighlight()
+	o.fixNormalPos()
+}
+
+// visualRange returns the selection including the rune under the cursor,
+// or the whole line in the linewise visual mode.
+func (o *opVim) visualRange() (int, int) {
+	if o.visualLine {
+		return 0, o.op.buf.Len()
+	}
+	start, end := o.visualStart, o.op.buf.Pos()
+	if end < start {
+		start, end = end, start
+	}
+	end++
+	if o.op.buf.Len() < end {
+		end = o.op.buf.Len()
+	}
+	return start, end
+}
+
+func (o *opVim) refreshVisual() {
+	start, end := o.visualRange()
+	o.op.buf.SetHighlight(start, end, "7")
+}
+
+func (o *opVim) HandleVimVisual(r rune, readNext func() rune) (t rune) {
 	switch r {
 	case CharEnter, CharInterrupt:
+		o.ExitVimVisualMode()
 		o.ExitVimMode()
 		return r
 	}
 
-	if r, handled := o.handleVimNormalMovement(r, readNext); handled {
-		return r
-	}
+	selected := false
+	defer func() {
+		if !selected {
+			o.register = 0
+		}
+	}()
 
-	if r, handled := o.handleVimNormalEnterInsert(r, readNext); handled {
-		return r
-	}
+	rb := o.op.buf
+	count, r := o.readCount(r, readNext)
 
-	// invalid operation
-	o.op.t.Bell()
+	switch r {
+	case '"':
+		if reg := readNext(); isVimRegister(reg) {
+			o.register = reg
+			selected = true
+		} else {
+			o.bell()
+		}
+	case CharEsc:
+		o.ExitVimVisualMode()
+	case 'v', 'V':
+		// the other key switches between characterwise and linewise
+		if o.visualLine == (r == 'V') {
+			o.ExitVimVisualMode()
+			break
+		}
+		o.visualLine = r == 'V'
+		o.refreshVisual()
+	case 'o':
+		pos := rb.Pos()
+		rb.SetPos(o.visualStart)
+		o.visualStart = pos
+		o.refreshVisual()
+	case 'y', 'd', 'x', 'c':
+		start, end := o.visualRange()
+		o.ExitVimVisualMode()
+		if r == 'x' {
+			r = 'd'
+		}
+		o.applyOperator(r, start, end)
+	case '~':
+		start, end := o.visualRange()
+		rb.ToggleCaseRange(start, end)
+		rb.SetPos(start)
+		o.ExitVimVisualMode()
+	case 'i', 'a':
+		key := readNext()
+		rs := rb.Runes()
+		start, end, ok := vimTextObject(rs, o.wordClasses(rs, key == 'W'), rb.Pos(), key, r == 'a')
+		if !ok {
+			o.bell()
+			return 0
+		}
+		o.visualStart = start
+		o.visualLine = false
+		rb.SetPos(end - 1)
+		o.refreshVisual()
+	default:
+		target, _, ok := o.motion(r, vimCount(count), rb.Pos(), readNext)
+		if !ok {
+			o.bell()
+			return 0
+		}
+		rb.SetPos(target)
+		o.fixNormalPos()
+		o.refreshVisual()
+	}
 	return 0
 }
 
-func (o *opVim) EnterVimInsertMode() {
-	o.vimMode = VIM_INSERT
-}
-
-func (o *opVim) ExitVimInsertMode() {
-	o.vimMode = VIM_NORMAL
-}
-
 func (o *opVim) HandleVim(r rune, readNext func() rune) rune {
-	if o.vimMode == VIM_NORMAL {
+	switch o.vimMode {
+	case VIM_NORMAL:
 		return o.HandleVimNormal(r, readNext)
+	case VIM_VISUAL:
+		return o.HandleVimVisual(r, readNext)
+	}
+
+	if o.inserting {
+		switch r {
+		case CharEnter, CharCtrlJ, CharInterrupt:
+			o.inserting = false
+		default:
+			o.changeKeys = append(o.changeKeys, r)
+		}
 	}
 	if r == CharEsc {
 		o.ExitVimInsertMode()
+		if o.inserting {
+			o.lastChange, o.lastChangeCount = o.changeKeys, o.changeCount
+			o.changeKeys, o.inserting = nil, false
+		}
 		return 0
 	}
-
-	switch o.vimMode {
-	case VIM_INSERT:
-		return r
-	case VIM_VISUAL:
-	}
 	return r
 }
@@ -0,0 +1,234 @@
+package readline
+
+import (
+	"unicode"
+)
+
+// vimNextWordStart returns the start of the next word after pos.
+func vimNextWordStart(classes []int, pos int) int {
+	if len(classes) <= pos {
+		return len(classes)
+	}
+	i := pos
+	if c := classes[i]; c != 0 {
+		for i < len(classes) && classes[i] == c {
+			i++
+		}
+	}
+	for i < len(classes) && classes[i] == 0 {
+		i++
+	}
+	return i
+}
+
+// vimPrevWordStart returns the start of the word before pos.
+func vimPrevWordStart(classes []int, pos int) int {
+	if len(classes) < pos {
+		pos = len(classes)
+	}
+	return backwardWord(classes, pos)
+}
+
+// vimWordEnd returns the last rune of the word after pos.
+func vimWordEnd(classes []int, pos int) int {
+	i := pos + 1
+	for i < len(classes) && classes[i] == 0 {
+		i++
+	}
+	if len(classes) <= i {
+		return len(classes) - 1
+	}
+	c := classes[i]
+	for i+1 < len(classes) && classes[i+1] == c {
+		i++
+	}
+	return i
+}
+
+// vimFirstNonBlank returns the position of the first non-blank rune.
+func vimFirstNonBlank(rs []rune) int {
+	for i, r := range rs {
+		if !unicode.IsSpace(r) {
+			return i
+		}
+	}
+	return 0
+}
+
+// vimFindChar returns the position reached by a f, F, t or T motion.
+// When repeat is true, a t or T motion does not stop in front of the
+// rune next to the cursor.
+func vimFindChar(rs []rune, pos int, kind rune, ch rune, repeat bool) (int, bool) {
+	switch kind {
+	case 'f', 't':
+		i := pos + 1
+		if kind == 't' && repeat {
+			i++
+		}
+		for ; i < len(rs); i++ {
+			if rs[i] == ch {
+				if kind == 't' {
+					return i - 1, true
+				}
+				return i, true
+			}
+		}
+	case 'F', 'T':
+		i := pos - 1
+		if kind == 'T' && repeat {
+			i--
+		}
+		for ; 0 <= i; i-- {
+			if rs[i] == ch {
+				if kind == 'T' {
+					return i + 1, true
+				}
+				return i, true
+			}
+		}
+	}
+	return pos, false
+}
+
+// vimWordObject returns the range of the iw, aw, iW or aW text object.
+func vimWordObject(classes []int, pos int, around bool) (int, int) {
+	if len(classes) <= pos {
+		return pos, pos
+	}
+
+	span := func(start, end int) (int, int) {
+		c := classes[start]
+		for 0 < start && classes[start-1] == c {
+			start--
+		}
+		for end < len(classes) && classes[end] == c {
+			end++
+		}
+		return start, end
+	}
+
+	start, end := span(pos, pos)
+	if !around {
+		return start, end
+	}
+
+	if classes[pos] == 0 {
+		if end < len(classes) {
+			_, end = span(end, end)
+		}
+		return start, end
+	}
+	if end < len(classes) && classes[end] == 0 {
+		_, end = span(end, end)
+	} else if 0 < start && classes[start-1] == 0 {
+		start, _ = span(start-1, start-1)
+	}
+	return start, end
+}
+
+// vimPairObject returns the range of a text object delimited by brackets.
+func vimPairObject(rs []rune, pos int, open rune, close rune, around bool) (int, int, bool) {
+	if len(rs) <= pos {
+		return pos, pos, false
+	}
+
+	start := -1
+	depth := 0
+	i := pos
+	if rs[pos] == close {
+		i--
+	}
+	for ; 0 <= i; i-- {
+		switch rs[i] {
+		case close:
+			depth++
+		case open:
+			if depth == 0 {
+				start = i
+			} else {
+				depth--
+			}
+		}
+		if -1 < start {
+			break
+		}
+	}
+	if start < 0 {
+		return pos, pos, false
+	}
+
+	depth = 0
+	for i = start + 1; i < len(rs); i++ {
+		switch rs[i] {
+		case open:
+			depth++
+		case close:
+			if depth == 0 {
+				if around {
+					return start, i + 1, true
+				}
+				return start + 1, i, true
+			}
+			depth--
+		}
+	}
+	return pos, pos, false
+}
+
+// vimQuoteObject returns the range of a text object delimited by quotes.
+// If the cursor is not in a quoted string, the next string is used.
+func vimQuoteObject(rs []rune, pos int, quote rune, around bool) (int, int, bool) {
+	var quotes []int
+	for i := 0; i < len(rs); i++ {
+		if rs[i] == '\\' {
+			i++
+			continue
+		}
+		if rs[i] == quote {
+			quotes = append(quotes, i)
+		}
+	}
+
+	for i := 0; i+1 < len(quotes); i += 2 {
+		start, end := quotes[i], quotes[i+1]
+		if end < pos {
+			continue
+		}
+		if !around {
+			return start + 1, end, true
+		}
+		end++
+		if end < len(rs) && unicode.IsSpace(rs[end]) {
+			for end < len(rs) && unicode.IsSpace(rs[end]) {
+				end++
+			}
+		} else {
+			for 0 < start && unicode.IsSpace(rs[start-1]) {
+				start--
+			}
+		}
+		return start, end, true
+	}
+	return pos, pos, false
+}
+
+// vimTextObject returns the range of the text object specified by key.
+// The word objects use classes.
+func vimTextObject(rs []rune, classes []int, pos int, key rune, around bool) (int, int, bool) {
+	switch key {
+	case 'w', 'W':
+		start, end := vimWordObject(classes, pos, around)
+		return start, end, start < end
+	case '(', ')', 'b':
+		return vimPairObject(rs, pos, '(', ')', around)
+	case '[', ']':
+		return vimPairObject(rs, pos, '[', ']', around)
+	case '{', '}', 'B':
+		return vimPairObject(rs, pos, '{', '}', around)
+	case '<', '>':
+		return vimPairObject(rs, pos, '<', '>', around)
+	case '"', '\'', '`':
+		return vimQuoteObject(rs, pos, key, around)
+	}
+	return pos, pos, false
+}
@@ -0,0 +1,290 @@
+package readline
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func newTestVim(buf string, idx int) *opVim {
+	op := &Operation{buf: new(RuneBuffer)}
+	op.buf.WriteString(buf)
+	op.buf.idx = idx
+	o := &opVim{cfg: &Config{VimMode: true}, op: op}
+	o.ExitVimInsertMode()
+	return o
+}
+
+func feedVim(o *opVim, keys string) {
+	rs := []rune(keys)
+	read := func() rune {
+		if len(rs) < 1 {
+			return CharEsc
+		}
+		r := rs[0]
+		rs = rs[1:]
+		return r
+	}
+	readNext := func() rune {
+		return o.ReadVimRune(read)
+	}
+	for 0 < len(rs) || 0 < len(o.pending) {
+		r := readNext()
+		if t := o.HandleVim(r, readNext); t != 0 && o.vimMode == VIM_INSERT {
+			o.op.buf.WriteRune(t)
+		}
+	}
+}
+
+var vimVisualTests = []struct {
+	Buf       string
+	Idx       int
+	Keys      string
+	Expect    string
+	ExpectIdx int
+	Kill      string
+}{
+	{
+		Buf:       "select id from t",
+		Idx:       7,
+		Keys:      "Vvld",
+		Expect:    "select  from t",
+		ExpectIdx: 7,
+		Kill:      "id",
+	},
+	{
+		Buf:       "select id from t",
+		Idx:       8,
+		Keys:      "Vvhhhy",
+		Expect:    "select id from t",
+		ExpectIdx: 5,
+		Kill:      "t id",
+	},
+	{
+		Buf:       "select id from t",
+		Idx:       0,
+		Keys:      "Vve~",
+		Expect:    "SELECT id from t",
+		ExpectIdx: 0,
+	},
+	{
+		Buf:       "select id from t",
+		Idx:       10,
+		Keys:      "Vv$cT",
+		Expect:    "select id T",
+		ExpectIdx: 11,
+		Kill:      "from t",
+	},
+	{
+		Buf:       "select id from t",
+		Idx:       7,
+		Keys:      "Vvlohx",
+		Expect:    "select from t",
+		ExpectIdx: 6,
+		Kill:      " id",
+	},
+	{
+		Buf:       "select id from t",
+		Idx:       7,
+		Keys:      "Vd",
+		Expect:    "",
+		ExpectIdx: 0,
+		Kill:      "select id from t",
+	},
+	{
+		Buf:       "select id from t",
+		Idx:       7,
+		Keys:      "VvlV~",
+		Expect:    "SELECT ID FROM T",
+		ExpectIdx: 0,
+	},
+	{
+		Buf:       "select id from t",
+		Idx:       7,
+		Keys:      "VvVvly",
+		Expect:    "select id from t",
+		ExpectIdx: 7,
+		Kill:      "id",
+	},
+}
+
+func TestOpVim_Visual(t *testing.T) {
+	for _, v := range vimVisualTests {
+		o := newTestVim(v.Buf, v.Idx)
+		feedVim(o, v.Keys)
+		result := string(o.op.buf.Runes())
+		if result != v.Expect || o.op.buf.Pos() != v.ExpectIdx {
+			t.Errorf("result = %q (%d), want %q (%d) for %q", result, o.op.buf.Pos(), v.Expect, v.ExpectIdx, v.Keys)
+		}
+		if v.Kill != "" && string(o.op.buf.lastKill) != v.Kill {
+			t.Errorf("kill = %q, want %q for %q", string(o.op.buf.lastKill), v.Kill, v.Keys)
+		}
+		if o.op.buf.hlEnd != 0 {
+			t.Errorf("highlight is not cleared for %q", v.Keys)
+		}
+	}
+}
+
+var vimNormalTests = []struct {
+	Buf       string
+	Idx       int
+	Keys      string
+	Expect    string
+	ExpectIdx int
+	Kill      string
+}{
+	{Buf: "select id from t", Idx: 7, Keys: "d$", Expect: "select ", ExpectIdx: 6, Kill: "id from t"},
+	{Buf: "select id from t", Idx: 0, Keys: "c2wupdate", Expect: "update from t", ExpectIdx: 6, Kill: "select id"},
+	{Buf: "select id from t", Idx: 0, Keys: "3x", Expect: "ect id from t", ExpectIdx: 0, Kill: "sel"},
+	{Buf: "select id from t", Idx: 7, Keys: "yw", Expect: "select id from t", ExpectIdx: 7, Kill: "id "},
+	{Buf: "select id from t", Idx: 7, Keys: "de", Expect: "select  from t", ExpectIdx: 7, Kill: "id"},
+	{Buf: "count(id, name)", Idx: 6, Keys: "dt)", Expect: "count()", ExpectIdx: 6, Kill: "id, name"},
+	{Buf: "count(id, name)", Idx: 8, Keys: "ci(*", Expect: "count(*)", ExpectIdx: 7, Kill: "id, name"},
+	{Buf: "select \"a b\" from t", Idx: 9, Keys: "da\"", Expect: "select from t", ExpectIdx: 7, Kill: "\"a b\" "},
+	{Buf: "select `a b` from t", Idx: 0, Keys: "di`", Expect: "select `` from t", ExpectIdx: 8, Kill: "a b"},
+	{Buf: "select id from t", Idx: 8, Keys: "diw", Expect: "select  from t", ExpectIdx: 7, Kill: "id"},
+	{Buf: "select id from t", Idx: 8, Keys: "daw", Expect: "select from t", ExpectIdx: 7, Kill: "id "},
+	{Buf: "a,b,c,d", Idx: 0, Keys: "f,;;D", Expect: "a,b,c", ExpectIdx: 4, Kill: ",d"},
+	{Buf: "a,b,c,d", Idx: 6, Keys: "2F,,x", Expect: "a,b,cd", ExpectIdx: 5, Kill: ","},
+	{Buf: "select id from t", Idx: 0, Keys: "2d2w", Expect: "", ExpectIdx: 0, Kill: "select id from t"},
+	{Buf: "select id", Idx: 0, Keys: "cwupdate", Expect: "update id", ExpectIdx: 6, Kill: "select"},
+	{Buf: "abc", Idx: 1, Keys: "ylp", Expect: "abbc", ExpectIdx: 2, Kill: "b"},
+	{Buf: "abc", Idx: 0, Keys: "2rx", Expect: "xxc", ExpectIdx: 1},
+	{Buf: "abc", Idx: 0, Keys: "2~", Expect: "ABc", ExpectIdx: 2},
+}
+
+func TestOpVim_Normal(t *testing.T) {
+	for _, v := range vimNormalTests {
+		o := newTestVim(v.Buf, v.Idx)
+		feedVim(o, v.Keys)
+		result := string(o.op.buf.Runes())
+		if result != v.Expect || o.op.buf.Pos() != v.ExpectIdx {
+			t.Errorf("result = %q (%d), want %q (%d) for %q", result, o.op.buf.Pos(), v.Expect, v.ExpectIdx, v.Keys)
+		}
+		if v.Kill != "" && string(o.op.buf.lastKill) != v.Kill {
+			t.Errorf("kill = %q, want %q for %q", string(o.op.buf.lastKill), v.Kill, v.Keys)
+		}
+	}
+}
+
+type testClipboard struct {
+	text []rune
+}
+
+func (c *testClipboard) ReadClipboard() ([]rune, error) {
+	return c.text, nil
+}
+
+func (c *testClipboard) WriteClipboard(text []rune) error {
+	c.text = text
+	return nil
+}
+
+var vimRepeatTests = []struct {
+	Buf       string
+	Idx       int
+	Keys      string
+	Expect    string
+	ExpectIdx int
+}{
+	{Buf: "a b c d e", Idx: 0, Keys: "dw..", Expect: "d e", ExpectIdx: 0},
+	{Buf: "a b c d e", Idx: 0, Keys: "dw2.", Expect: "d e", ExpectIdx: 0},
+	{Buf: "a b c", Idx: 0, Keys: "cwx\033w.", Expect: "x x c", ExpectIdx: 3},
+	{Buf: "a b c", Idx: 0, Keys: "Ax\033..", Expect: "a b cxxx", ExpectIdx: 8},
+	{Buf: "a b c", Idx: 0, Keys: "\"ayw\"Aywwdw\"aP", Expect: "a a a c", ExpectIdx: 5},
+	{Buf: "a b c", Idx: 0, Keys: "\"+yw$x\"+p", Expect: "a b a ", ExpectIdx: 5},
+	{Buf: "a,b,c", Idx: 0, Keys: "qaf,r;q@a", Expect: "a;b;c", ExpectIdx: 3},
+	{Buf: "1234567", Idx: 0, Keys: "qqxlq2@q", Expect: "2467", ExpectIdx: 3},
+	{Buf: "1 2 3 4", Idx: 0, Keys: "qqxlq@q@@", Expect: "   4", ExpectIdx: 3},
+}
+
+func TestOpVim_Repeat(t *testing.T) {
+	for _, v := range vimRepeatTests {
+		o := newTestVim(v.Buf, v.Idx)
+		o.cfg.Clipboard = &testClipboard{}
+		feedVim(o, v.Keys)
+		result := string(o.op.buf.Runes())
+		if result != v.Expect || o.op.buf.Pos() != v.ExpectIdx {
+			t.Errorf("result = %q (%d), want %q (%d) for %q", result, o.op.buf.Pos(), v.Expect, v.ExpectIdx, v.Keys)
+		}
+	}
+}
+
+func TestOpVim_ModeIndicator(t *testing.T) {
+	out := &bytes.Buffer{}
+	o := newTestVim("abc", 0)
+	o.op.t = &Terminal{cfg: &Config{Stdout: out}}
+	o.op.prompt = "> "
+	o.cfg.VimModePrefix = map[int]string{VIM_NORMAL: "[N] ", VIM_INSERT: "[I] "}
+	o.cfg.VimCursorShape = true
+
+	tests := []struct {
+		Keys   string
+		Prompt string
+		Output string
+	}{
+		{Keys: "i", Prompt: "[I] > ", Output: "\033[6 q"},
+		{Keys: "\033", Prompt: "[N] > ", Output: "\033[2 q"},
+		{Keys: "V", Prompt: "> ", Output: ""},
+		{Keys: "\033", Prompt: "[N] > ", Output: ""},
+	}
+	for _, v := range tests {
+		out.Reset()
+		feedVim(o, v.Keys)
+		if string(o.op.buf.prompt) != v.Prompt {
+			t.Errorf("prompt = %q, want %q for %q", string(o.op.buf.prompt), v.Prompt, v.Keys)
+		}
+		if out.String() != v.Output {
+			t.Errorf("output = %q, want %q for %q", out.String(), v.Output, v.Keys)
+		}
+	}
+
+	o.cfg.FuncVimModePrompt = func(mode int, prompt string) string {
+		if mode == VIM_INSERT {
+			return prompt
+		}
+		return strings.TrimSpace(prompt) + ": "
+	}
+	feedVim(o, "a")
+	if string(o.op.buf.prompt) != "> " {
+		t.Errorf("prompt = %q, want %q", string(o.op.buf.prompt), "> ")
+	}
+
+	o.op.t.SetCursorShape(CursorDefault)
+	if out.String() != "\033[6 q\033[0 q" {
+		t.Errorf("output = %q, want cursor shape to be restored", out.String())
+	}
+}
+
+func TestOpVim_Cancel(t *testing.T) {
+	for _, keys := range []string{"d", "di", "df", "dt", "c2", "r", "\"", "q", "@", "2", "2d", "Vi"} {
+		o := newTestVim("abc def", 0)
+		o.op.canceled = true
+		rs := []rune(keys)
+		read := func() rune {
+			if len(rs) < 1 {
+				return charCancel
+			}
+			r := rs[0]
+			rs = rs[1:]
+			return r
+		}
+		readNext := func() rune {
+			return o.ReadVimRune(read)
+		}
+		for o.HandleVim(readNext(), readNext) == 0 && 0 < len(rs) {
+		}
+
+		result := string(o.op.buf.Runes())
+		if result != "abc def" || o.lastChange != nil || o.macroReg != 0 || o.lastFindKind != 0 {
+			t.Errorf("result = %q, change %q, macro %q, find %q for %q", result, string(o.lastChange), o.macroReg, o.lastFindKind, keys)
+		}
+	}
+
+	o := newTestVim("abc", 0)
+	o.macroReg = 'a'
+	o.ReadVimRune(func() rune { return charCancel })
+	if len(o.macro) != 0 {
+		t.Errorf("macro = %q, want no keys", string(o.macro))
+	}
+}
@@ -0,0 +1,118 @@
+package readline
+
+import (
+	"unicode"
+)
+
+// WordClassifier defines the words for word operations.
+//
+// Classify returns the class of each rune in line. Adjacent runes of the
+// same class form a word, and runes of class 0 separate words.
+type WordClassifier interface {
+	Classify(line []rune) []int
+}
+
+// WordClassifierFunc classifies each rune independently.
+type WordClassifierFunc func(r rune) int
+
+func (f WordClassifierFunc) Classify(line []rune) []int {
+	classes := make([]int, len(line))
+	for i, r := range line {
+		classes[i] = f(r)
+	}
+	return classes
+}
+
+// UnicodeWords treats Unicode letters, marks and digits as word runes.
+var UnicodeWords WordClassifier = WordClassifierFunc(func(r rune) int {
+	if unicode.IsLetter(r) || unicode.IsMark(r) || unicode.IsDigit(r) {
+		return 1
+	}
+	return 0
+})
+
+// forwardWord returns the end of the word after i.
+func forwardWord(classes []int, i int) int {
+	for i < len(classes) && classes[i] == 0 {
+		i++
+	}
+	if i < len(classes) {
+		c := classes[i]
+		for i < len(classes) && classes[i] == c {
+			i++
+		}
+	}
+	return i
+}
+
+// backwardWord returns the start of the word before i.
+func backwardWord(classes []int, i int) int {
+	for 0 < i && classes[i-1] == 0 {
+		i--
+	}
+	if 0 < i {
+		c := classes[i-1]
+		for 0 < i && classes[i-1] == c {
+			i--
+		}
+	}
+	return i
+}
+
+// SQLIdentifierWords treats SQL identifiers, consisting of Unicode letters,
+// marks, digits and underscores, as words. A backquoted identifier is a
+// single word of its own class, even if it contains spaces.
+var SQLIdentifierWords WordClassifier = sqlIdentifierWords{}
+
+type sqlIdentifierWords struct{}
+
+func (sqlIdentifierWords) Classify(line []rune) []int {
+	classes := make([]int, len(line))
+	for i := 0; i < len(line); i++ {
+		r := line[i]
+		switch {
+		case r == '`':
+			start := i
+			for i++; i < len(line) && line[i] != '`'; i++ {
+				if line[i] == '\\' && i+1 < len(line) {
+					i++
+				}
+			}
+			for j := start; j <= i && j < len(line); j++ {
+				classes[j] = 2
+			}
+		case r == '_', unicode.IsLetter(r), unicode.IsMark(r), unicode.IsDigit(r):
+			classes[i] = 1
+		}
+	}
+	return classes
+}
+
+// VimWords defines words like the vim word: a sequence of letters, digits
+// and underscores, or a sequence of other non-blank runes.
+var VimWords WordClassifier = WordClassifierFunc(func(r rune) int {
+	switch {
+	case unicode.IsSpace(r):
+		return 0
+	case r == '_', unicode.IsLetter(r), unicode.IsMark(r), unicode.IsDigit(r):
+		return 1
+	}
+	return 2
+})
+
+// VimWORDs defines words like the vim WORD: a sequence of non-blank runes.
+var VimWORDs WordClassifier = WordClassifierFunc(func(r rune) int {
+	if unicode.IsSpace(r) {
+		return 0
+	}
+	return 1
+})
+
+// PathWords treats path components as words, so that Ctrl+W removes one
+// component of a path at a time.
+var PathWords WordClassifier = WordClassifierFunc(func(r rune) int {
+	if unicode.IsSpace(r) || r == '/' || r == '\\' {
+		return 0
+	}
+	return 1
+})
@@ -0,0 +1,34 @@
+package readline
+
+import (
+	"testing"
+)
+
+var wordClassifierTests = []struct {
+	Classifier WordClassifier
+	Line       string
+	Idx        int
+	Expect     string
+}{
+	{Classifier: UnicodeWords, Line: "select tbl.col_name", Idx: 19, Expect: "select tbl.col_"},
+	{Classifier: SQLIdentifierWords, Line: "select tbl.col_name", Idx: 19, Expect: "select tbl."},
+	{Classifier: SQLIdentifierWords, Line: "select `my table`", Idx: 17, Expect: "select "},
+	{Classifier: SQLIdentifierWords, Line: "select t.`a\\` b`", Idx: 16, Expect: "select t."},
+	{Classifier: SQLIdentifierWords, Line: "t`a b`", Idx: 6, Expect: "t"},
+	{Classifier: VimWords, Line: "count(id)", Idx: 9, Expect: "count(id"},
+	{Classifier: VimWORDs, Line: "select count(id)", Idx: 16, Expect: "select "},
+	{Classifier: PathWords, Line: "load /tmp/data.csv", Idx: 18, Expect: "load /tmp/"},
+}
+
+func TestWordClassifier(t *testing.T) {
+	for _, v := range wordClassifierTests {
+		buf := new(RuneBuffer)
+		buf.cfg = &Config{KillWordClassifier: v.Classifier}
+		buf.Set([]rune(v.Line))
+		buf.idx = v.Idx
+		buf.BackEscapeWord()
+		if string(buf.Runes()) != v.Expect {
+			t.Errorf("result = %q, want %q for %q", string(buf.Runes()), v.Expect, v.Line)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package readline
+
+// yankArgState is the argument inserted by the last Meta+.
+type yankArgState struct {
+	start int
+	end   int
+	back  int
+}
+
+// SplitSQLArgs splits line into arguments in the manner of SQL.
+// Adjacent tokens such as "t.col" or "`file.csv`" make an argument, and
+// commas, semicolons, parentheses and operators separate arguments.
+func SplitSQLArgs(line []rune) [][]rune {
+	isSeparator := func(t SQLToken) bool {
+		return t.Type == SQLTokenPunct && !t.IsPunct('.') && !t.IsPunct('*')
+	}
+
+	args := [][]rune{}
+	start, end := -1, -1
+	for _, t := range TokenizeSQL(line) {
+		if isSeparator(t) || t.Start != end {
+			if -1 < start {
+				args = append(args, line[start:end])
+			}
+			start, end = -1, -1
+		}
+		if isSeparator(t) {
+			continue
+		}
+		if start < 0 {
+			start = t.Start
+		}
+		end = t.End
+	}
+	if -1 < start {
+		args = append(args, line[start:end])
+	}
+	return args
+}
+
+// SplitShellArgs splits line into arguments in the manner of a shell.
+// The arguments keep their quotes.
+func SplitShellArgs(line []rune) [][]rune {
+	args := [][]rune{}
+	for _, s := range ParseSegments(line, len(line)) {
+		if s.Start < s.End {
+			args = append(args, line[s.Start:s.End])
+		}
+	}
+	return args
+}
+
+// YankLastArg inserts the last argument of the previous history entry.
+// Repeating it replaces the inserted argument with the last argument of
+// the entry before.
+func (o *Operation) YankLastArg() {
+	back := 1
+	if o.yankArg != nil {
+		back = o.yankArg.back + 1
+	}
+	split := o.GetConfig().FuncSplitArgs
+	for ; ; back++ {
+		entry, ok := o.getHistory().Recent(back)
+		if !ok {
+			o.t.Bell()
+			return
+		}
+		args := split(entry)
+		if len(args) < 1 {
+			continue
+		}
+
+		arg := args[len(args)-1]
+		if o.yankArg == nil {
+			start := o.buf.Pos()
+			o.buf.WriteRunes(arg)
+			o.yankArg = &yankArgState{start: start}
+		} else {
+			o.buf.ReplaceRange(o.yankArg.start, o.yankArg.end, arg)
+		}
+		o.yankArg.end = o.yankArg.start + len(arg)
+		o.yankArg.back = back
+		return
+	}
+}
+
+// YankNthArg inserts the nth argument of the previous history entry.
+// The first word is the 0th, and a negative n counts from the last one.
+func (o *Operation) YankNthArg(n int) {
+	entry, _ := o.getHistory().Recent(1)
+	args := o.GetConfig().FuncSplitArgs(entry)
+	if n < 0 {
+		n += len(args)
+	}
+	if n < 0 || len(args) <= n {
+		o.t.Bell()
+		return
+	}
+	o.buf.WriteRunes(args[n])
+}
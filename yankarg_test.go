@@ -0,0 +1,62 @@
+package readline
+
+import (
+	"strings"
+	"testing"
+)
+
+var splitArgsTests = []struct {
+	Split  func([]rune) [][]rune
+	Line   string
+	Expect []string
+}{
+	{Split: SplitSQLArgs, Line: "select * from `users.csv`;", Expect: []string{"select", "*", "from", "`users.csv`"}},
+	{Split: SplitSQLArgs, Line: "select t.id, count(*) from data.csv t", Expect: []string{"select", "t.id", "count", "*", "from", "data.csv", "t"}},
+	{Split: SplitSQLArgs, Line: "where name = 'a b' -- comment", Expect: []string{"where", "name", "'a b'"}},
+	{Split: SplitSQLArgs, Line: "", Expect: []string{}},
+	{Split: SplitShellArgs, Line: "cp 'a b.csv' dir/", Expect: []string{"cp", "'a b.csv'", "dir/"}},
+}
+
+func TestSplitArgs(t *testing.T) {
+	for _, v := range splitArgsTests {
+		args := v.Split([]rune(v.Line))
+		result := make([]string, 0, len(args))
+		for _, a := range args {
+			result = append(result, string(a))
+		}
+		if strings.Join(result, "|") != strings.Join(v.Expect, "|") {
+			t.Errorf("result = %q, want %q for %q", result, v.Expect, v.Line)
+		}
+	}
+}
+
+func TestOperation_YankArg(t *testing.T) {
+	o := &Operation{
+		cfg:     &Config{FuncSplitArgs: SplitSQLArgs},
+		buf:     new(RuneBuffer),
+		history: newOpHistory(&Config{HistoryLimit: 10}),
+	}
+	for _, s := range []string{"select * from `a.csv`", ";", "select id from b.csv"} {
+		o.history.Push([]rune(s))
+	}
+	o.history.Push(nil)
+	o.buf.WriteString("x  y")
+	o.buf.idx = 2
+
+	o.YankLastArg()
+	if string(o.buf.Runes()) != "x b.csv y" {
+		t.Errorf("result = %q, want %q", string(o.buf.Runes()), "x b.csv y")
+	}
+	o.YankLastArg()
+	if string(o.buf.Runes()) != "x `a.csv` y" || o.buf.Pos() != 9 {
+		t.Errorf("result = %q (%d), want %q (%d)", string(o.buf.Runes()), o.buf.Pos(), "x `a.csv` y", 9)
+	}
+
+	o.yankArg = nil
+	o.buf.Set(nil)
+	o.YankNthArg(1)
+	o.YankNthArg(-2)
+	if string(o.buf.Runes()) != "idfrom" {
+		t.Errorf("result = %q, want %q", string(o.buf.Runes()), "idfrom")
+	}
+}